@@ -0,0 +1,371 @@
+// Package api serves the client to scripts and other apps on localhost: JSON
+// endpoints for the common actions and the frontend events over a WebSocket.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/client"
+	"greet/domain"
+	"greet/relays"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events queued for a WebSocket client that is not reading are dropped past
+// this
+const STREAM_BUFFER = 256
+
+// Server is the local API. It only listens on the loopback interface and
+// every request needs the token.
+type Server struct {
+	client *client.Client
+	token  string
+	http   *http.Server
+
+	mu      sync.Mutex
+	streams map[chan []byte]bool
+}
+
+func New(c *client.Client, token string) *Server {
+	s := &Server{
+		client:  c,
+		token:   token,
+		streams: map[chan []byte]bool{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/post", s.auth(s.handlePost))
+	mux.HandleFunc("/follow", s.auth(s.handleFollow))
+	mux.HandleFunc("/unfollow", s.auth(s.handleUnfollow))
+	mux.HandleFunc("/feed", s.auth(s.handleFeed))
+	mux.HandleFunc("/profile", s.auth(s.handleProfile))
+	mux.HandleFunc("/relays", s.auth(s.handleRelays))
+	mux.HandleFunc("/events", s.auth(s.handleEvents))
+	s.http = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// NewToken makes a random token for a config that has none yet
+func NewToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Start listens on localhost:port and serves in the background. Events go
+// out to WebSocket clients from then on.
+func (s *Server) Start(port int) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return err
+	}
+	s.client.Events.AddSink(s)
+	log.Info().Msgf("Local API listening on %s", ln.Addr())
+	go func() {
+		err := s.http.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Msgf("Local API stopped: %s", err.Error())
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Stop() {
+	s.client.Events.RemoveSink(s)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.http.Shutdown(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for stream := range s.streams {
+		close(stream)
+		delete(s.streams, stream)
+	}
+}
+
+// Emit makes the server an EventSink, passing events on to every WebSocket
+// client as {"name": ..., "payload": ...}
+func (s *Server) Emit(name string, payload interface{}) {
+	j, err := json.Marshal(map[string]interface{}{"name": name, "payload": payload})
+	if err != nil {
+		log.Debug().Msgf("Could not encode %s for the local API: %s", name, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for stream := range s.streams {
+		select {
+		case stream <- j:
+		default:
+			log.Debug().Msgf("Local API client is behind, dropped %s", name)
+		}
+	}
+}
+
+// auth checks the token, from an Authorization: Bearer header or, for
+// WebSockets opened from a browser, a token query parameter
+func (s *Server) auth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("Bad or missing token"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// readBody decodes a JSON POST body, answering the request itself when it
+// is not one
+func readBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("POST only"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func (s *Server) loggedIn(w http.ResponseWriter) bool {
+	if s.client.Config.PrivKeyHex == "" {
+		writeError(w, http.StatusConflict, errors.New("Not logged in"))
+		return false
+	}
+	return true
+}
+
+// handlePost publishes a note: {"content": "...", "tags": [["t", "..."]]}
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Content string     `json:"content"`
+		Tags    nostr.Tags `json:"tags"`
+	}
+	if !readBody(w, r, &body) || !s.loggedIn(w) {
+		return
+	}
+	if strings.TrimSpace(body.Content) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("Nothing to send"))
+		return
+	}
+	if body.Tags == nil {
+		body.Tags = nostr.Tags{}
+	}
+	writeJson(w, s.client.PostEvent(nostr.KindTextNote, body.Tags, body.Content))
+}
+
+// handleFollow adds users to the contact list: {"users": ["npub1...", ...]}.
+// With "force": true it publishes even if no contact list was found.
+func (s *Server) handleFollow(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Users []string `json:"users"`
+		Force bool     `json:"force"`
+	}
+	if !readBody(w, r, &body) || !s.loggedIn(w) {
+		return
+	}
+	pks := []string{}
+	for _, user := range body.Users {
+		pk, err := s.client.ResolvePubkey(user)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		pks = append(pks, pk)
+	}
+	if len(pks) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("Give the users to follow"))
+		return
+	}
+	var diff *domain.ContactListDiff
+	var err error
+	if body.Force {
+		diff, err = s.client.FollowContactConfirmed(pks)
+	} else {
+		diff, err = s.client.FollowContact(pks)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJson(w, diff)
+}
+
+// handleUnfollow removes a user from the contact list: {"user": "npub1..."}
+func (s *Server) handleUnfollow(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		User string `json:"user"`
+	}
+	if !readBody(w, r, &body) || !s.loggedIn(w) {
+		return
+	}
+	pk, err := s.client.ResolvePubkey(body.User)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	diff, err := s.client.UnfollowContact(pk)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJson(w, diff)
+}
+
+// handleFeed lists the notes of a feed: /feed?feed=contacts&since=6h. With
+// count or until it returns a page instead, newest first, and the until for
+// the next: /feed?count=50&until=1700000000
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	feedId := r.URL.Query().Get("feed")
+	if feedId == "" {
+		feedId = s.client.GetSelectedFeed()
+	}
+	if r.URL.Query().Has("count") || r.URL.Query().Has("until") {
+		s.handleFeedPage(w, r, feedId)
+		return
+	}
+	since := r.URL.Query().Get("since")
+	if since == "" {
+		since = "6h"
+	}
+	ts, err := domain.ParseSince(since)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	events, err := s.client.QueryFeed(feedId, ts)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJson(w, events)
+}
+
+func (s *Server) handleFeedPage(w http.ResponseWriter, r *http.Request, feedId string) {
+	var until, count int64
+	for name, v := range map[string]*int64{"until": &until, "count": &count} {
+		if param := r.URL.Query().Get(name); param != "" {
+			n, err := strconv.ParseInt(param, 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, errors.New("Bad "+name+": "+param))
+				return
+			}
+			*v = n
+		}
+	}
+	page, err := s.client.LoadOlder(feedId, nostr.Timestamp(until), int(count))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJson(w, page)
+}
+
+// handleProfile returns a profile: /profile?user=name@example.com, our own
+// without a user
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	pk := s.client.Config.Pubkey
+	if user := r.URL.Query().Get("user"); user != "" {
+		var err error
+		pk, err = s.client.ResolvePubkey(user)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if pk == "" {
+		writeError(w, http.StatusConflict, errors.New("Not logged in"))
+		return
+	}
+	profile, err := s.client.GetContactProfile(pk)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJson(w, profile)
+}
+
+func (s *Server) handleRelays(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, struct {
+		Relays []*relays.Relay    `json:"relays"`
+		Status client.RelayStatus `json:"status"`
+	}{s.client.GetRelays(), s.client.GetRelayStatus()})
+}
+
+// handleEvents upgrades to a WebSocket and streams events until the client
+// goes away
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	conn, _, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		log.Debug().Msgf("Local API WebSocket upgrade failed: %s", err.Error())
+		return
+	}
+	stream := make(chan []byte, STREAM_BUFFER)
+	s.mu.Lock()
+	s.streams[stream] = true
+	s.mu.Unlock()
+
+	// Anything the client sends is ignored, reading only notices it leaving
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := wsutil.ReadClientData(conn); err != nil {
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		s.mu.Lock()
+		if s.streams[stream] {
+			delete(s.streams, stream)
+			close(stream)
+		}
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	for {
+		select {
+		case msg, ok := <-stream:
+			if !ok {
+				return
+			}
+			if err := wsutil.WriteServerText(conn, msg); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
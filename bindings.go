@@ -0,0 +1,356 @@
+package main
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/client"
+	"greet/domain"
+	"greet/relays"
+)
+
+// The methods of the client the frontend calls. Each one only passes the
+// call on; methods taking readers, writers or contexts are left out, as
+// Wails cannot bind them.
+
+func (a *App) GetArticles(pk string, drafts bool) []*domain.Article {
+	return a.client.GetArticles(pk, drafts)
+}
+
+func (a *App) GetArticle(naddr string) (*domain.Article, error) {
+	return a.client.GetArticle(naddr)
+}
+
+func (a *App) PublishArticle(article domain.Article, draft bool) (*domain.Article, error) {
+	return a.client.PublishArticle(article, draft)
+}
+
+func (a *App) GetBookmarkLists() []*domain.BookmarkList {
+	return a.client.GetBookmarkLists()
+}
+
+func (a *App) GetBookmarks(name string) []*domain.Bookmark {
+	return a.client.GetBookmarks(name)
+}
+
+func (a *App) AddBookmark(name string, bookmarkType string, value string, private bool) error {
+	return a.client.AddBookmark(name, bookmarkType, value, private)
+}
+
+func (a *App) RemoveBookmark(name string, bookmarkType string, value string) error {
+	return a.client.RemoveBookmark(name, bookmarkType, value)
+}
+
+func (a *App) GetBookmarkedEvents(name string) []*nostr.Event {
+	return a.client.GetBookmarkedEvents(name)
+}
+
+func (a *App) BeginSubscriptions() {
+	a.client.BeginSubscriptions()
+}
+
+func (a *App) DumpEvents() {
+	a.client.DumpEvents()
+}
+
+func (a *App) RefreshContactProfiles() {
+	a.client.RefreshContactProfiles()
+}
+
+func (a *App) LoadContactList() []string {
+	return a.client.LoadContactList()
+}
+
+func (a *App) RefreshFeed(repost bool) {
+	a.client.RefreshFeed(repost)
+}
+
+func (a *App) RefreshFeedReset() {
+	a.client.RefreshFeedReset()
+}
+
+func (a *App) PkToNpub(pk string) (string, error) {
+	return a.client.PkToNpub(pk)
+}
+
+func (a *App) GetContactList(pk string) []string {
+	return a.client.GetContactList(pk)
+}
+
+func (a *App) GetTaggedProfiles(parentEvent string) []*domain.Profile {
+	return a.client.GetTaggedProfiles(parentEvent)
+}
+
+func (a *App) GetTaggedEvents(parentEvent string) []*nostr.Event {
+	return a.client.GetTaggedEvents(parentEvent)
+}
+
+func (a *App) GetContactProfile(pk string) (*domain.Profile, error) {
+	return a.client.GetContactProfile(pk)
+}
+
+func (a *App) GetReadableRelays() []*string {
+	return a.client.GetReadableRelays()
+}
+
+func (a *App) GetWritableRelays() []*string {
+	return a.client.GetWritableRelays()
+}
+
+func (a *App) GetRelays() []*relays.Relay {
+	return a.client.GetRelays()
+}
+
+func (a *App) SetRelays(r []*relays.Relay) {
+	a.client.SetRelays(r)
+}
+
+func (a *App) GetTextNotesForPubkeys(pks []string, postEvent string, repost bool) error {
+	return a.client.GetTextNotesForPubkeys(pks, postEvent, repost)
+}
+
+func (a *App) GetTextNotesByEventIds(ids []string) []*nostr.Event {
+	return a.client.GetTextNotesByEventIds(ids)
+}
+
+func (a *App) PostEvent(kind int, tags nostr.Tags, content string) *nostr.Event {
+	return a.client.PostEvent(kind, tags, content)
+}
+
+func (a *App) PublishContentToSelectedRelays(kind int, content string, ts [][]string, relays []string) {
+	a.client.PublishContentToSelectedRelays(kind, content, ts, relays)
+}
+
+func (a *App) FollowContact(pk []string) (*domain.ContactListDiff, error) {
+	return a.client.FollowContact(pk)
+}
+
+func (a *App) FollowContactConfirmed(pk []string) (*domain.ContactListDiff, error) {
+	return a.client.FollowContactConfirmed(pk)
+}
+
+func (a *App) UnfollowContact(pk string) (*domain.ContactListDiff, error) {
+	return a.client.UnfollowContact(pk)
+}
+
+func (a *App) GetMyPubkey() string {
+	return a.client.GetMyPubkey()
+}
+
+func (a *App) SaveConfigDark(dark bool) {
+	a.client.SaveConfigDark(dark)
+}
+
+func (a *App) SetLoginWithPrivKey(keypin []string) error {
+	return a.client.SetLoginWithPrivKey(keypin)
+}
+
+func (a *App) LoginWithPin(pin string) error {
+	return a.client.LoginWithPin(pin)
+}
+
+func (a *App) GenerateKeys() (*map[string]string, error) {
+	return a.client.GenerateKeys()
+}
+
+func (a *App) SaveNewKeys(creds map[string]string) error {
+	return a.client.SaveNewKeys(creds)
+}
+
+func (a *App) SaveProfile(metadata domain.ProfileMetadata) error {
+	return a.client.SaveProfile(metadata)
+}
+
+func (a *App) GetRelayStatus() client.RelayStatus {
+	return a.client.GetRelayStatus()
+}
+
+func (a *App) CheckRelays() {
+	a.client.CheckRelays()
+}
+
+func (a *App) PingTimer() {
+	a.client.PingTimer()
+}
+
+func (a *App) GetContactListVersions() []*domain.ContactListVersion {
+	return a.client.GetContactListVersions()
+}
+
+func (a *App) DiffContactListVersions(fromId string, toId string) (*domain.ContactListDiff, error) {
+	return a.client.DiffContactListVersions(fromId, toId)
+}
+
+func (a *App) RestoreContactListVersion(id string) (*domain.ContactListDiff, error) {
+	return a.client.RestoreContactListVersion(id)
+}
+
+func (a *App) SaveContacts() (*string, error) {
+	return a.client.SaveContacts()
+}
+
+func (a *App) RestoreContacts() (*string, error) {
+	return a.client.RestoreContacts()
+}
+
+func (a *App) ParseContent(content string, tags [][]string) []*domain.ContentToken {
+	return a.client.ParseContent(content, tags)
+}
+
+func (a *App) DeleteEvent(evId string) error {
+	return a.client.DeleteEvent(evId)
+}
+
+func (a *App) SendDirectMessage(pk string, text string) (*nostr.Event, error) {
+	return a.client.SendDirectMessage(pk, text)
+}
+
+func (a *App) ExportEventsToFile(path string, filter nostr.Filter, all bool) (int, error) {
+	return a.client.ExportEventsToFile(path, filter, all)
+}
+
+func (a *App) ImportEventsFromFile(path string, relays []string) (*client.ImportResult, error) {
+	return a.client.ImportEventsFromFile(path, relays)
+}
+
+func (a *App) GetFollowSets() []*domain.FollowSet {
+	return a.client.GetFollowSets()
+}
+
+func (a *App) CreateFollowSet(name string, title string) error {
+	return a.client.CreateFollowSet(name, title)
+}
+
+func (a *App) SaveFollowSet(set domain.FollowSet) error {
+	return a.client.SaveFollowSet(set)
+}
+
+func (a *App) AddToFollowSet(name string, pk string, private bool) error {
+	return a.client.AddToFollowSet(name, pk, private)
+}
+
+func (a *App) RemoveFromFollowSet(name string, pk string) error {
+	return a.client.RemoveFromFollowSet(name, pk)
+}
+
+func (a *App) MoveToFollowSet(from string, to string, pk string) error {
+	return a.client.MoveToFollowSet(from, to, pk)
+}
+
+func (a *App) GetFeeds() []*domain.Feed {
+	return a.client.GetFeeds()
+}
+
+func (a *App) FeedPubkeys(feedId string) ([]string, error) {
+	return a.client.FeedPubkeys(feedId)
+}
+
+func (a *App) QueryFeed(feedId string, since nostr.Timestamp) ([]*nostr.Event, error) {
+	return a.client.QueryFeed(feedId, since)
+}
+
+func (a *App) SelectFeed(feedId string) error {
+	return a.client.SelectFeed(feedId)
+}
+
+func (a *App) GetSelectedFeed() string {
+	return a.client.GetSelectedFeed()
+}
+
+func (a *App) SubscribeToFeed(feedId string, repost bool) error {
+	return a.client.SubscribeToFeed(feedId, repost)
+}
+
+func (a *App) LoadMuteList() {
+	a.client.LoadMuteList()
+}
+
+func (a *App) GetMuteList() []*domain.MuteEntry {
+	return a.client.GetMuteList()
+}
+
+func (a *App) Mute(muteType string, value string, private bool) error {
+	return a.client.Mute(muteType, value, private)
+}
+
+func (a *App) Unmute(muteType string, value string) error {
+	return a.client.Unmute(muteType, value)
+}
+
+func (a *App) VerifyNip05(pk string) (bool, error) {
+	return a.client.VerifyNip05(pk)
+}
+
+func (a *App) LookupNip05(identifier string) (*domain.Profile, error) {
+	return a.client.LookupNip05(identifier)
+}
+
+func (a *App) Nip19Decode(uri string) (*domain.Nip19Entity, error) {
+	return a.client.Nip19Decode(uri)
+}
+
+func (a *App) EncodeNote(evId string) (string, error) {
+	return a.client.EncodeNote(evId)
+}
+
+func (a *App) EncodeEvent(evId string) (string, error) {
+	return a.client.EncodeEvent(evId)
+}
+
+func (a *App) EncodeProfile(pk string) (string, error) {
+	return a.client.EncodeProfile(pk)
+}
+
+func (a *App) EncodeEntity(pk string, kind int, identifier string) (string, error) {
+	return a.client.EncodeEntity(pk, kind, identifier)
+}
+
+func (a *App) EncodePrivateKey() (string, error) {
+	return a.client.EncodePrivateKey()
+}
+
+func (a *App) SubscribeToNotifications() {
+	a.client.SubscribeToNotifications()
+}
+
+func (a *App) GetNotifications() []*domain.Notification {
+	return a.client.GetNotifications()
+}
+
+func (a *App) GetUnreadNotificationCount() int {
+	return a.client.GetUnreadNotificationCount()
+}
+
+func (a *App) MarkNotificationsRead(ids []string) {
+	a.client.MarkNotificationsRead(ids)
+}
+
+func (a *App) MarkAllNotificationsRead() {
+	a.client.MarkAllNotificationsRead()
+}
+
+func (a *App) LoadOlder(feedId string, until nostr.Timestamp, count int) (*client.FeedPage, error) {
+	return a.client.LoadOlder(feedId, until, count)
+}
+
+func (a *App) GetEventProvenance(evId string) *relays.EventProvenance {
+	return a.client.GetEventProvenance(evId)
+}
+
+func (a *App) GetRelayRejectCounts() map[string]int64 {
+	return a.client.GetRelayRejectCounts()
+}
+
+func (a *App) SyncHistory(target string) error {
+	return a.client.SyncHistory(target)
+}
+
+func (a *App) CancelSync() {
+	a.client.CancelSync()
+}
+
+func (a *App) GetSyncProgress() *client.SyncProgress {
+	return a.client.GetSyncProgress()
+}
+
+func (a *App) GetThread(evId string) (*domain.Thread, error) {
+	return a.client.GetThread(evId)
+}
@@ -0,0 +1,643 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"greet/api"
+	"greet/client"
+	"greet/domain"
+	"greet/relays"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+)
+
+const cliUsage = `Usage: greet [-logger LEVEL] <command> [arguments]
+
+Without a command the desktop app starts. Commands:
+
+  post <text|->                        Publish a note, - reads it from stdin
+  feed [-since 6h] [-feed id] [-json]  Print notes from a feed
+  follow [-force] <user>...            Add users to your contact list, -force
+                                       starts a new one if none is found
+  unfollow <user>                      Remove a user from your contact list
+  profile get [user]                   Print a profile, yours by default
+  profile set <field=value>...         Change fields of your profile
+  relays list                          List configured relays
+  relays add [-read] [-write] <url>    Add a relay
+  relays remove <url>                  Remove a relay
+  dm send <user> <text|->              Send an encrypted direct message
+  export [-since 24h] [-kinds 1,3] [-authors users] [-limit n]
+                                       Write your events as JSON lines
+  import [-publish urls|-publish-all] <file|->
+                                       Read events from JSON lines, checking
+                                       signatures, and republish them. Without
+                                       a relay the file is only checked
+  sync <relay>                         Copy your history from your other relays
+                                       to one that is missing it
+  api [status|on|off] [-port 7447]     Show or change the local API setting
+  local-relay [status|on|off] [-port 4869]
+                                       Show or change the local relay setting
+
+Users are hex keys, npub/nprofile or name@domain. The key in the config
+file is used; set GREET_PIN if it is PIN protected, or GREET_NSEC to use
+another key.
+`
+
+// runCli runs one headless command using the same config and relays as the
+// desktop app, returning the exit code
+func runCli(a *App, args []string) int {
+	a.ctx = context.Background()
+	setupLogging(os.Stderr, a.logging)
+	// There is no frontend, so events are only recorded
+	a.load(client.NewRecorder())
+
+	err := a.runCommand(args, os.Stdin, os.Stdout)
+	a.client.Pool.DisconnectAll()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err.Error())
+		return 1
+	}
+	return 0
+}
+
+func (a *App) runCommand(args []string, in io.Reader, out io.Writer) error {
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprint(out, cliUsage)
+		return nil
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "post":
+		return a.cliPost(args, in, out)
+	case "feed":
+		return a.cliFeed(args, out)
+	case "follow":
+		return a.cliFollow(args, out, true)
+	case "unfollow":
+		return a.cliFollow(args, out, false)
+	case "profile":
+		return a.cliProfile(args, out)
+	case "relays":
+		return a.cliRelays(args, out)
+	case "dm":
+		return a.cliDm(args, in, out)
+	case "export":
+		return a.cliExport(args, out)
+	case "import":
+		return a.cliImport(args, in, out)
+	case "sync":
+		return a.cliSync(args, out)
+	case "api":
+		return a.cliApi(args, out)
+	case "local-relay":
+		return a.cliLocalRelay(args, out)
+	}
+	return fmt.Errorf("Unknown command %s, see greet help", cmd)
+}
+
+// cliLogin loads the private key the way OnDomReady does, taking the PIN or
+// an override key from the environment instead of a dialog
+func (a *App) cliLogin() error {
+	if nsec := os.Getenv("GREET_NSEC"); nsec != "" {
+		key := nsec
+		if strings.HasPrefix(key, "nsec") {
+			val, err := domain.DecodeNip19(key)
+			if err != nil {
+				return err
+			}
+			key = val.PrivKey
+		}
+		pk, err := nostr.GetPublicKey(key)
+		if err != nil {
+			return err
+		}
+		a.client.Config.PrivKeyHex, a.client.Config.Pubkey = key, pk
+	} else if strings.HasPrefix(a.client.Config.Privkey, "ENC:") {
+		pin := os.Getenv("GREET_PIN")
+		if pin == "" {
+			return errors.New("Private key is PIN protected, set GREET_PIN")
+		}
+		err := a.client.LoginWithPin(pin)
+		if err != nil {
+			return err
+		}
+	} else if a.client.Config.Privkey != "" {
+		pk, err := nostr.GetPublicKey(a.client.Config.Privkey)
+		if err != nil {
+			return err
+		}
+		a.client.Config.PrivKeyHex, a.client.Config.Pubkey = a.client.Config.Privkey, pk
+	} else {
+		return errors.New("No private key configured, log in with the desktop app or set GREET_NSEC")
+	}
+
+	a.connectRelays()
+	if len(a.client.Pool.Relays()) == 0 {
+		return errors.New("No relays enabled, add one with greet relays add")
+	}
+	return nil
+}
+
+// readText returns the joined arguments, or stdin when the text is "-"
+func readText(args []string, in io.Reader) (string, error) {
+	text := strings.Join(args, " ")
+	if text == "-" {
+		b, err := io.ReadAll(in)
+		if err != nil {
+			return "", err
+		}
+		text = strings.TrimRight(string(b), "\n")
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("Nothing to send")
+	}
+	return text, nil
+}
+
+func (a *App) cliPost(args []string, in io.Reader, out io.Writer) error {
+	text, err := readText(args, in)
+	if err != nil {
+		return err
+	}
+	if err = a.cliLogin(); err != nil {
+		return err
+	}
+	ev := a.client.PostEvent(nostr.KindTextNote, nostr.Tags{}, text)
+	note, _ := a.client.EncodeEvent(ev.ID)
+	fmt.Fprintln(out, note)
+	return nil
+}
+
+func (a *App) cliFeed(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("feed", flag.ContinueOnError)
+	since := fs.String("since", "6h", "How far back to go, a duration or unix time")
+	feedId := fs.String("feed", domain.FEED_CONTACTS, "Feed to print, contacts or a follow set")
+	asJson := fs.Bool("json", false, "Print events as JSON lines")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ts, err := domain.ParseSince(*since)
+	if err != nil {
+		return err
+	}
+	if err = a.cliLogin(); err != nil {
+		return err
+	}
+
+	a.client.LoadContactList()
+	events, err := a.client.QueryFeed(*feedId, ts)
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		for _, ev := range events {
+			fmt.Fprintln(out, ev.String())
+		}
+		return nil
+	}
+
+	authors := []string{}
+	for _, ev := range events {
+		if !domain.Contains(authors, ev.PubKey) && !a.client.DB.HasProfile(ev.PubKey) {
+			authors = append(authors, ev.PubKey)
+		}
+	}
+	for _, chk := range domain.ChunkSlice(authors, client.QUERY_SIZE) {
+		a.client.GetMetadataEvents(chk)
+	}
+	for _, ev := range events {
+		content := ev.Content
+		if ev.Kind == nostr.KindBoost {
+			content = "[boost] " + domain.TagValue(ev.Tags, "e")
+		}
+		fmt.Fprintf(out, "%s  %s: %s\n", ev.CreatedAt.Time().Format("2006-01-02 15:04"), a.displayName(ev.PubKey), content)
+	}
+	return nil
+}
+
+func (a *App) displayName(pk string) string {
+	if p := a.client.DB.GetProfile(pk); p != nil {
+		if p.Meta.DisplayName != "" {
+			return p.Meta.DisplayName
+		}
+		if p.Meta.Name != "" {
+			return p.Meta.Name
+		}
+	}
+	if len(pk) > 12 {
+		return pk[:12]
+	}
+	return pk
+}
+
+func (a *App) cliFollow(args []string, out io.Writer, follow bool) error {
+	force := false
+	if follow {
+		fs := flag.NewFlagSet("follow", flag.ContinueOnError)
+		fs.BoolVar(&force, "force", false, "Publish even if no contact list is found or it looks truncated")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		args = fs.Args()
+	}
+	if len(args) == 0 || (!follow && len(args) != 1) {
+		return errors.New("Give the user to follow or unfollow")
+	}
+	pks := []string{}
+	for _, user := range args {
+		pk, err := a.client.ResolvePubkey(user)
+		if err != nil {
+			return err
+		}
+		pks = append(pks, pk)
+	}
+	if err := a.cliLogin(); err != nil {
+		return err
+	}
+
+	var diff *domain.ContactListDiff
+	var err error
+	if follow && force {
+		diff, err = a.client.FollowContactConfirmed(pks)
+	} else if follow {
+		diff, err = a.client.FollowContact(pks)
+	} else {
+		diff, err = a.client.UnfollowContact(pks[0])
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d added, %d removed, now following %d\n", len(diff.Added), len(diff.Removed), diff.After)
+	return nil
+}
+
+func (a *App) cliProfile(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("Use profile get or profile set")
+	}
+	if err := a.cliLogin(); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		pk := a.client.Config.Pubkey
+		if len(args) > 1 {
+			var err error
+			pk, err = a.client.ResolvePubkey(args[1])
+			if err != nil {
+				return err
+			}
+		}
+		profile, err := a.client.GetContactProfile(pk)
+		if err != nil {
+			return err
+		}
+		j, err := json.MarshalIndent(profile, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(j))
+		return nil
+	case "set":
+		if len(args) < 2 {
+			return errors.New("Give the fields to set as field=value")
+		}
+		// Start from the published content so fields we don't know about survive
+		filter := nostr.Filter{Authors: []string{a.client.Config.Pubkey}, Kinds: []int{nostr.KindSetMetadata}}
+		for _, ev := range a.client.Pool.QueryAll(&filter) {
+			a.client.DB.AddEvent(ev.ID, ev)
+		}
+		meta := make(map[string]interface{})
+		if ev := a.client.DB.GetReplaceableEvent(a.client.Config.Pubkey, nostr.KindSetMetadata, ""); ev != nil {
+			if err := json.Unmarshal([]byte(ev.Content), &meta); err != nil {
+				return err
+			}
+		}
+		for _, field := range args[1:] {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok || k == "" {
+				return errors.New("Expected field=value, got " + field)
+			}
+			meta[k] = v
+		}
+		content, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		ev := a.client.PostEvent(nostr.KindSetMetadata, nostr.Tags{}, string(content))
+		fmt.Fprintln(out, ev.ID)
+		return nil
+	}
+	return errors.New("Unknown profile command " + args[0])
+}
+
+func (a *App) cliRelays(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("Use relays list, add or remove")
+	}
+	switch args[0] {
+	case "list":
+		for _, r := range a.client.Config.Relays {
+			status := "disabled"
+			if r.Enabled {
+				status = "enabled"
+			}
+			fmt.Fprintf(out, "%s read=%t write=%t %s\n", r.Url, r.Read, r.Write, status)
+		}
+		return nil
+	case "add":
+		fs := flag.NewFlagSet("relays add", flag.ContinueOnError)
+		read := fs.Bool("read", true, "Read from the relay")
+		write := fs.Bool("write", true, "Write to the relay")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("Give the relay URL to add")
+		}
+		url := nostr.NormalizeURL(fs.Arg(0))
+		if !strings.HasPrefix(url, "ws") {
+			return errors.New("Not a relay URL: " + fs.Arg(0))
+		}
+		for _, r := range a.client.Config.Relays {
+			if r.Url == url {
+				r.Read, r.Write, r.Enabled = *read, *write, true
+				return a.client.Config.Save()
+			}
+		}
+		a.client.Config.Relays = append(a.client.Config.Relays, &relays.Relay{Url: url, Read: *read, Write: *write, Enabled: true})
+		return a.client.Config.Save()
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("Give the relay URL to remove")
+		}
+		url := nostr.NormalizeURL(args[1])
+		kept := []*relays.Relay{}
+		for _, r := range a.client.Config.Relays {
+			if r.Url != url && r.Url != args[1] {
+				kept = append(kept, r)
+			}
+		}
+		if len(kept) == len(a.client.Config.Relays) {
+			return errors.New("No relay " + args[1])
+		}
+		a.client.Config.Relays = kept
+		return a.client.Config.Save()
+	}
+	return errors.New("Unknown relays command " + args[0])
+}
+
+// progressPrinter prints history sync progress as it is reported
+type progressPrinter struct {
+	out io.Writer
+}
+
+func (p progressPrinter) Emit(name string, payload interface{}) {
+	progress, ok := payload.(client.SyncProgress)
+	if name != client.EV_SYNC_PROGRESS || !ok {
+		return
+	}
+	switch progress.State {
+	case client.SYNC_FETCHING:
+		fmt.Fprintf(p.out, "Fetching from %s: %d events found\n", progress.Relay, progress.Found)
+	case client.SYNC_PUBLISHING:
+		fmt.Fprintf(p.out, "Publishing: %d of %d\n", progress.Published+progress.Failed, progress.Missing)
+	}
+}
+
+// cliSync copies our history to a relay, stopping cleanly on Ctrl-C
+func (a *App) cliSync(args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("Give the relay to copy your history to")
+	}
+	if err := a.cliLogin(); err != nil {
+		return err
+	}
+	target := args[0]
+	if a.client.Pool.GetRelayByUrl(target) == nil {
+		target = nostr.NormalizeURL(target)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	a.client.Events.AddSink(progressPrinter{out: os.Stderr})
+	progress, err := a.client.SyncHistoryTo(ctx, target)
+	if progress != nil {
+		fmt.Fprintf(out, "%d events found, %d on %s already, %d published, %d failed\n",
+			progress.Found, progress.OnTarget, target, progress.Published, progress.Failed)
+	}
+	return err
+}
+
+// cliApi changes the local API setting. The desktop app picks it up when it
+// next starts.
+func (a *App) cliApi(args []string, out io.Writer) error {
+	cmd := "status"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "status":
+	case "on":
+		fs := flag.NewFlagSet("api on", flag.ContinueOnError)
+		port := fs.Int("port", a.client.Config.ApiPort, "Port to listen on, on localhost only")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		a.client.Config.ApiEnabled, a.client.Config.ApiPort = true, *port
+		if a.client.Config.ApiToken == "" {
+			a.client.Config.ApiToken = api.NewToken()
+		}
+	case "off":
+		a.client.Config.ApiEnabled = false
+	default:
+		return errors.New("Use api status, on or off")
+	}
+	if cmd != "status" {
+		if err := a.client.Config.Save(); err != nil {
+			return err
+		}
+	}
+
+	status := a.GetApiStatus()
+	if !status.Enabled {
+		fmt.Fprintln(out, "Local API off")
+		return nil
+	}
+	fmt.Fprintf(out, "Local API on at %s\nToken %s\n", status.Url, status.Token)
+	return nil
+}
+
+// cliLocalRelay changes the local relay setting, which the desktop app picks
+// up when it next starts
+func (a *App) cliLocalRelay(args []string, out io.Writer) error {
+	cmd := "status"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "status":
+	case "on":
+		fs := flag.NewFlagSet("local-relay on", flag.ContinueOnError)
+		port := fs.Int("port", a.client.Config.LocalRelayPort, "Port to listen on, on localhost only")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		a.client.Config.LocalRelayEnabled, a.client.Config.LocalRelayPort = true, *port
+	case "off":
+		a.client.Config.LocalRelayEnabled = false
+	default:
+		return errors.New("Use local-relay status, on or off")
+	}
+	if cmd != "status" {
+		if err := a.client.Config.Save(); err != nil {
+			return err
+		}
+	}
+
+	status := a.GetLocalRelayStatus()
+	if !status.Enabled {
+		fmt.Fprintln(out, "Local relay off")
+		return nil
+	}
+	fmt.Fprintf(out, "Local relay on at %s\n", status.Url)
+	return nil
+}
+
+func (a *App) cliDm(args []string, in io.Reader, out io.Writer) error {
+	if len(args) < 3 || args[0] != "send" {
+		return errors.New("Use dm send <user> <text|->")
+	}
+	pk, err := a.client.ResolvePubkey(args[1])
+	if err != nil {
+		return err
+	}
+	text, err := readText(args[2:], in)
+	if err != nil {
+		return err
+	}
+	if err = a.cliLogin(); err != nil {
+		return err
+	}
+	ev, err := a.client.SendDirectMessage(pk, text)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, ev.ID)
+	return nil
+}
+
+func (a *App) cliExport(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	since := fs.String("since", "", "Only events after this, a duration or unix time")
+	kinds := fs.String("kinds", "", "Comma separated kinds, all by default")
+	authors := fs.String("authors", "", "Comma separated users instead of yourself")
+	limit := fs.Int("limit", 0, "Only the newest events, all by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := a.cliLogin(); err != nil {
+		return err
+	}
+
+	filter := nostr.Filter{Authors: []string{a.client.Config.Pubkey}, Limit: *limit}
+	if *authors != "" {
+		filter.Authors = []string{}
+		for _, user := range strings.Split(*authors, ",") {
+			pk, err := a.client.ResolvePubkey(strings.TrimSpace(user))
+			if err != nil {
+				return err
+			}
+			filter.Authors = append(filter.Authors, pk)
+		}
+	}
+	if *since != "" {
+		ts, err := domain.ParseSince(*since)
+		if err != nil {
+			return err
+		}
+		filter.Since = &ts
+	}
+	for _, k := range strings.Split(*kinds, ",") {
+		if k = strings.TrimSpace(k); k == "" {
+			continue
+		}
+		kind, err := strconv.Atoi(k)
+		if err != nil {
+			return errors.New("Bad kind " + k)
+		}
+		filter.Kinds = append(filter.Kinds, kind)
+	}
+
+	_, err := a.client.ExportEvents(out, filter, true)
+	return err
+}
+
+// cliImport reads an export back in, checking every event, and sends the
+// events on to relays. Without any it is only a check of the file.
+func (a *App) cliImport(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	publish := fs.String("publish", "", "Comma separated write relays to republish to")
+	publishAll := fs.Bool("publish-all", false, "Republish to all write relays")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("Give the file to import, or - for stdin")
+	}
+
+	targets := []string{}
+	for _, r := range a.client.Config.Relays {
+		if !r.Enabled || !r.Write {
+			continue
+		}
+		selected := *publishAll
+		for _, url := range strings.Split(*publish, ",") {
+			url = strings.TrimSpace(url)
+			if url != "" && (r.Url == url || r.Url == nostr.NormalizeURL(url)) {
+				selected = true
+			}
+		}
+		if selected {
+			targets = append(targets, r.Url)
+		}
+	}
+	if (*publish != "" || *publishAll) && len(targets) == 0 {
+		return errors.New("No enabled write relays to publish to, add them with greet relays add")
+	}
+	if len(targets) > 0 {
+		a.connectRelays()
+	}
+
+	var result *client.ImportResult
+	var err error
+	if fs.Arg(0) == "-" {
+		result, err = a.client.ImportEvents(in, targets)
+	} else {
+		result, err = a.client.ImportEventsFromFile(fs.Arg(0), targets)
+	}
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		// The cache lives only as long as this process
+		fmt.Fprintf(out, "%d events read, %d valid, %d invalid\n", result.Read, result.Imported+result.Skipped, result.Invalid)
+		fmt.Fprintln(out, "Nothing was kept, give -publish or -publish-all to send the events to relays")
+		return nil
+	}
+	fmt.Fprintf(out, "%d events read, %d imported, %d skipped, %d invalid\n", result.Read, result.Imported, result.Skipped, result.Invalid)
+	for _, url := range targets {
+		fmt.Fprintf(out, "%s took %d\n", url, result.Published[url])
+	}
+	return nil
+}
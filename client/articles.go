@@ -0,0 +1,142 @@
+package client
+
+import (
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (c *Client) articleFromEvent(ev *nostr.Event) *domain.Article {
+	article := domain.Article{
+		Id:         ev.ID,
+		PubKey:     ev.PubKey,
+		Kind:       ev.Kind,
+		Identifier: domain.TagValue(ev.Tags, "d"),
+		Title:      domain.TagValue(ev.Tags, "title"),
+		Summary:    domain.TagValue(ev.Tags, "summary"),
+		Image:      domain.TagValue(ev.Tags, "image"),
+		CreatedAt:  ev.CreatedAt,
+		Hashtags:   []string{},
+		Content:    ev.Content,
+	}
+	// Older articles without published_at were first published when created
+	article.PublishedAt = int64(ev.CreatedAt)
+	if ts, err := strconv.ParseInt(domain.TagValue(ev.Tags, "published_at"), 10, 64); err == nil {
+		article.PublishedAt = ts
+	}
+	for _, tag := range ev.Tags.GetAll([]string{"t", ""}) {
+		if !domain.Contains(article.Hashtags, tag.Value()) {
+			article.Hashtags = append(article.Hashtags, tag.Value())
+		}
+	}
+	article.Naddr, _ = nip19.EncodeEntity(ev.PubKey, ev.Kind, article.Identifier, c.relayHints(ev))
+	return &article
+}
+
+// GetArticles lists the articles of pk, newest first. Our own drafts are
+// included when drafts is set.
+func (c *Client) GetArticles(pk string, drafts bool) []*domain.Article {
+	log.Debug().Msgf("GetArticles for %s", pk)
+	filter := nostr.Filter{
+		Authors: []string{pk},
+		Kinds:   []int{domain.KIND_ARTICLE},
+	}
+	if drafts && pk == c.Config.Pubkey {
+		filter.Kinds = append(filter.Kinds, domain.KIND_ARTICLE_DRAFT)
+	}
+	for _, ev := range c.Pool.QueryAll(&filter) {
+		c.DB.AddEvent(ev.ID, ev)
+	}
+
+	articles := []*domain.Article{}
+	for _, ev := range c.DB.QueryEvents(&filter) {
+		articles = append(articles, c.articleFromEvent(ev))
+	}
+	sort.Slice(articles, func(i, j int) bool {
+		return articles[i].PublishedAt > articles[j].PublishedAt
+	})
+	return articles
+}
+
+// GetArticle fetches the newest version of an article from its naddr, asking
+// the relays in the pointer as well as our own
+func (c *Client) GetArticle(naddr string) (*domain.Article, error) {
+	entity, err := domain.DecodeNip19(naddr)
+	if err != nil {
+		return nil, err
+	}
+	if entity.Type != "naddr" || !domain.IsArticleKind(entity.Kind) {
+		return nil, errors.New("Not an article address: " + naddr)
+	}
+
+	events := c.Pool.QueryWithHints(&nostr.Filter{
+		Authors: []string{entity.PubKey},
+		Kinds:   []int{entity.Kind},
+		Tags:    nostr.TagMap{"d": []string{entity.Identifier}},
+	}, entity.Relays)
+	for _, ev := range events {
+		c.DB.AddEvent(ev.ID, ev)
+	}
+
+	ev := c.DB.GetReplaceableEvent(entity.PubKey, entity.Kind, entity.Identifier)
+	if ev == nil {
+		return nil, errors.New("Article not found: " + naddr)
+	}
+	return c.articleFromEvent(ev), nil
+}
+
+// PublishArticle publishes a new article or a new version of an existing one,
+// as a draft if asked. The first publication date is kept across edits.
+func (c *Client) PublishArticle(article domain.Article, draft bool) (*domain.Article, error) {
+	if c.Config.PrivKeyHex == "" {
+		return nil, errors.New("Private key not available")
+	}
+	if strings.TrimSpace(article.Title) == "" {
+		return nil, errors.New("Articles need a title")
+	}
+
+	kind := domain.KIND_ARTICLE
+	if draft {
+		kind = domain.KIND_ARTICLE_DRAFT
+	}
+	if article.Identifier == "" {
+		article.Identifier = domain.Slugify(article.Title)
+	}
+
+	tags := nostr.Tags{
+		{"d", article.Identifier},
+		{"title", article.Title},
+	}
+	if article.Summary != "" {
+		tags = append(tags, nostr.Tag{"summary", article.Summary})
+	}
+	if article.Image != "" {
+		tags = append(tags, nostr.Tag{"image", article.Image})
+	}
+	if !draft {
+		// The cache may not hold the article yet, so the relays are asked
+		// for the version being replaced
+		published := nostr.Now()
+		if prev := c.getLatestList(domain.KIND_ARTICLE, article.Identifier); prev != nil {
+			published = nostr.Timestamp(c.articleFromEvent(prev).PublishedAt)
+		}
+		tags = append(tags, nostr.Tag{"published_at", strconv.FormatInt(int64(published), 10)})
+	}
+	for _, t := range article.Hashtags {
+		t = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(t), "#"))
+		if t != "" {
+			tags = tags.AppendUnique(nostr.Tag{"t", t})
+		}
+	}
+
+	ev := c.signAndPublish(kind, c.mentionTags(article.Content, tags), article.Content)
+	log.Info().Msgf("Published article %s (kind %d)", article.Identifier, kind)
+	published := c.articleFromEvent(ev)
+	c.Events.Article(published)
+	return published, nil
+}
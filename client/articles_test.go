@@ -0,0 +1,51 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+	"strconv"
+	"testing"
+)
+
+func TestPublishArticleKeepsPublishedAt(t *testing.T) {
+	c, rec := newTestClient(t)
+	first := nostr.Now() - 3600
+	addOwnEvent(t, c, domain.KIND_ARTICLE, nostr.Tags{
+		{"d", "my-post"},
+		{"title", "My post"},
+		{"published_at", strconv.FormatInt(int64(first), 10)},
+	}, "First version", nostr.Now()-10)
+
+	edited, err := c.PublishArticle(domain.Article{Identifier: "my-post", Title: "My post", Content: "Second version"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.PublishedAt != int64(first) {
+		t.Errorf("Published at %d after the edit, want %d", edited.PublishedAt, first)
+	}
+	if got := rec.Events(EV_ARTICLE); len(got) != 1 {
+		t.Errorf("%d article events, want 1", len(got))
+	}
+
+	fresh, err := c.PublishArticle(domain.Article{Title: "New post", Content: "Hello"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.PublishedAt < int64(nostr.Now()-5) {
+		t.Errorf("New article published at %d, want now", fresh.PublishedAt)
+	}
+}
+
+func TestPublishArticleDraft(t *testing.T) {
+	c, _ := newTestClient(t)
+	draft, err := c.PublishArticle(domain.Article{Title: "Draft", Content: "Not yet"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draft.Kind != domain.KIND_ARTICLE_DRAFT {
+		t.Errorf("Kind %d, want a draft", draft.Kind)
+	}
+	if _, err := c.PublishArticle(domain.Article{Content: "No title"}, false); err == nil {
+		t.Error("Published an article without a title")
+	}
+}
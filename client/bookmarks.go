@@ -0,0 +1,197 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"sort"
+)
+
+// parseBookmarkList reads a bookmark list event. If the private items cannot
+// be decrypted the public ones come back with the error, which is enough to
+// show but not to publish from.
+func (c *Client) parseBookmarkList(ev *nostr.Event) (*domain.BookmarkList, error) {
+	list := domain.BookmarkList{
+		Kind:  ev.Kind,
+		Items: []*domain.Bookmark{},
+	}
+	if d := ev.Tags.GetFirst([]string{"d", ""}); d != nil {
+		list.Name = d.Value()
+	}
+	if title := ev.Tags.GetFirst([]string{"title", ""}); title != nil {
+		list.Title = title.Value()
+	}
+
+	for _, tag := range ev.Tags {
+		if isBookmarkTag(tag) {
+			list.Items = append(list.Items, &domain.Bookmark{Type: tag.Key(), Value: tag.Value()})
+		}
+	}
+	private, err := c.decryptPrivateTags(ev.Content)
+	if err != nil {
+		return &list, fmt.Errorf("Could not decrypt private bookmarks in %s: %w", ev.ID, err)
+	}
+	for _, tag := range private {
+		if isBookmarkTag(tag) {
+			list.Items = append(list.Items, &domain.Bookmark{Type: tag.Key(), Value: tag.Value(), Private: true})
+		}
+	}
+	return &list, nil
+}
+
+func (c *Client) getBookmarkList(name string) (*domain.BookmarkList, error) {
+	ev := c.getLatestList(domain.BookmarkListKind(name), name)
+	if ev == nil {
+		return &domain.BookmarkList{
+			Kind:  domain.BookmarkListKind(name),
+			Name:  name,
+			Items: []*domain.Bookmark{},
+		}, nil
+	}
+	return c.parseBookmarkList(ev)
+}
+
+// readBookmarkList is getBookmarkList for display, where the public items
+// are still worth showing
+func (c *Client) readBookmarkList(name string) *domain.BookmarkList {
+	list, err := c.getBookmarkList(name)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+	return list
+}
+
+func (c *Client) GetBookmarkLists() []*domain.BookmarkList {
+	lists := []*domain.BookmarkList{c.readBookmarkList("")}
+
+	sets := []*domain.BookmarkList{}
+	for _, ev := range c.getLatestSets(domain.KIND_BOOKMARK_SET) {
+		list, err := c.parseBookmarkList(ev)
+		if err != nil {
+			log.Error().Msg(err.Error())
+		}
+		sets = append(sets, list)
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Name < sets[j].Name
+	})
+	return append(lists, sets...)
+}
+
+func (c *Client) GetBookmarks(name string) []*domain.Bookmark {
+	return c.readBookmarkList(name).Items
+}
+
+func (c *Client) AddBookmark(name string, bookmarkType string, value string, private bool) error {
+	if !domain.IsBookmarkType(bookmarkType) {
+		return errors.New("Unknown bookmark type: " + bookmarkType)
+	}
+	if value == "" {
+		return errors.New("Nothing to bookmark")
+	}
+
+	list, err := c.getBookmarkList(name)
+	if err != nil {
+		return fmt.Errorf("%w, not changing the list", err)
+	}
+	items := []*domain.Bookmark{}
+	for _, b := range list.Items {
+		if b.Type != bookmarkType || b.Value != value {
+			items = append(items, b)
+		}
+	}
+	list.Items = append(items, &domain.Bookmark{Type: bookmarkType, Value: value, Private: private})
+
+	return c.publishBookmarkList(list)
+}
+
+func (c *Client) RemoveBookmark(name string, bookmarkType string, value string) error {
+	list, err := c.getBookmarkList(name)
+	if err != nil {
+		return fmt.Errorf("%w, not changing the list", err)
+	}
+	items := []*domain.Bookmark{}
+	for _, b := range list.Items {
+		if b.Type != bookmarkType || b.Value != value {
+			items = append(items, b)
+		}
+	}
+	list.Items = items
+
+	return c.publishBookmarkList(list)
+}
+
+// GetBookmarkedEvents resolves the bookmarked notes of a list, from cache
+// where possible
+func (c *Client) GetBookmarkedEvents(name string) []*nostr.Event {
+	events := []*nostr.Event{}
+	missing := []string{}
+	ids := []string{}
+
+	for _, b := range c.GetBookmarks(name) {
+		if b.Type != "e" {
+			continue
+		}
+		ids = append(ids, b.Value)
+		if !c.DB.HasEvent(b.Value) {
+			missing = append(missing, b.Value)
+		}
+	}
+	if len(missing) > 0 {
+		c.GetTextNotesByEventIds(missing)
+	}
+
+	for _, id := range ids {
+		ev := c.DB.GetEvent(id)
+		if ev != nil && !c.Mutes.IsMuted(ev) {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// publishBookmarkList publishes the list over the latest version, keeping
+// the tags that are not bookmarks, public and private
+func (c *Client) publishBookmarkList(list *domain.BookmarkList) error {
+	public := nostr.Tags{}
+	private := nostr.Tags{}
+	if list.Kind == domain.KIND_BOOKMARK_SET {
+		public = append(public, nostr.Tag{"d", list.Name})
+	}
+	if latest := c.getLatestList(list.Kind, list.Name); latest != nil {
+		var err error
+		private, err = c.decryptPrivateTags(latest.Content)
+		if err != nil {
+			return fmt.Errorf("Could not decrypt private bookmarks in %s: %w, not changing the list", latest.ID, err)
+		}
+		public = latest.Tags
+	}
+	if list.Kind == domain.KIND_BOOKMARK_SET {
+		public = domain.SetTagValue(public, "title", list.Title)
+	}
+
+	publicItems := nostr.Tags{}
+	privateItems := nostr.Tags{}
+	for _, b := range list.Items {
+		if b.Private {
+			privateItems = append(privateItems, nostr.Tag{b.Type, b.Value})
+		} else {
+			publicItems = append(publicItems, nostr.Tag{b.Type, b.Value})
+		}
+	}
+	public = domain.ReplaceListItems(public, isBookmarkTag, publicItems)
+	private = domain.ReplaceListItems(private, isBookmarkTag, privateItems)
+
+	content, err := c.encryptPrivateTags(private)
+	if err != nil {
+		return err
+	}
+	c.signAndPublish(list.Kind, public, content)
+	return nil
+}
+
+func isBookmarkTag(tag nostr.Tag) bool {
+	return domain.IsBookmarkType(tag.Key()) && tag.Value() != ""
+}
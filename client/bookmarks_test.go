@@ -0,0 +1,83 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+	"reflect"
+	"testing"
+)
+
+func TestBookmarkListRoundTrip(t *testing.T) {
+	c, _ := newTestClient(t)
+	content, err := c.encryptPrivateTags(nostr.Tags{{"e", "aa"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addOwnEvent(t, c, domain.KIND_BOOKMARK_SET, nostr.Tags{{"d", "reading"}}, content, nostr.Now()-10)
+	if err := c.AddBookmark("reading", "t", "go", false); err != nil {
+		t.Fatal(err)
+	}
+
+	ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, domain.KIND_BOOKMARK_SET, "reading")
+	if ev.Tags.GetFirst([]string{"e"}) != nil {
+		t.Errorf("private bookmark published in the clear: %v", ev.Tags)
+	}
+	items := c.GetBookmarks("reading")
+	if len(items) != 2 {
+		t.Fatalf("got %d bookmarks, want 2", len(items))
+	}
+	for _, b := range items {
+		if b.Private != (b.Type == "e") {
+			t.Errorf("bookmark %+v", b)
+		}
+	}
+}
+
+func TestBookmarkRefusesUndecryptableList(t *testing.T) {
+	c, _ := newTestClient(t)
+	old := addOwnEvent(t, c, domain.KIND_BOOKMARK_LIST, nostr.Tags{{"e", "aa"}}, "not a payload", nostr.Now()-10)
+
+	if err := c.AddBookmark("", "e", "bb", false); err == nil {
+		t.Error("AddBookmark published over a list it could not decrypt")
+	}
+	if err := c.RemoveBookmark("", "e", "aa"); err == nil {
+		t.Error("RemoveBookmark published over a list it could not decrypt")
+	}
+	if ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, domain.KIND_BOOKMARK_LIST, ""); ev.ID != old.ID {
+		t.Errorf("list replaced by %s", ev.ID)
+	}
+	if items := c.GetBookmarks(""); len(items) != 1 {
+		t.Errorf("public bookmarks not shown: %v", items)
+	}
+}
+
+func TestBookmarkEditKeepsOtherTags(t *testing.T) {
+	c, _ := newTestClient(t)
+	content, err := c.encryptPrivateTags(nostr.Tags{{"e", "aa"}, {"x-unknown", "hidden"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addOwnEvent(t, c, domain.KIND_BOOKMARK_SET, nostr.Tags{
+		{"d", "reading"}, {"title", "Reading"}, {"image", "https://example.com/a.png"},
+		{"e", "bb", "wss://relay.example"}, {"x-unknown", "kept"},
+	}, content, nostr.Now()-10)
+
+	if err := c.RemoveBookmark("reading", "e", "aa"); err != nil {
+		t.Fatal(err)
+	}
+	ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, domain.KIND_BOOKMARK_SET, "reading")
+	want := nostr.Tags{
+		{"d", "reading"}, {"title", "Reading"}, {"image", "https://example.com/a.png"},
+		{"x-unknown", "kept"}, {"e", "bb", "wss://relay.example"},
+	}
+	if !reflect.DeepEqual(ev.Tags, want) {
+		t.Errorf("Tags after removing %v, want %v", ev.Tags, want)
+	}
+	private, err := c.decryptPrivateTags(ev.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(private, nostr.Tags{{"x-unknown", "hidden"}}) {
+		t.Errorf("Private tags after removing %v", private)
+	}
+}
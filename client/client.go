@@ -0,0 +1,768 @@
+// Package client is the nostr client behind the desktop app and the command
+// line: feeds, profiles, lists and publishing.
+package client
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/rs/zerolog/log"
+	"greet/config"
+	"greet/crypto"
+	"greet/domain"
+	"greet/relays"
+	"greet/storage"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	QUERY_SIZE   = 25
+	POLL_SECONDS = 60
+	SECS_6H      = 21600
+	SECS_12H     = 43200
+	SECS_24H     = 86400
+)
+
+// Client is a logged in nostr user: their config, relays, event cache and
+// lists. Events passes what happens on to whatever is showing it.
+type Client struct {
+	Config *config.Config
+	Pool   *relays.Pool
+	DB     *storage.DB
+	Mutes  *domain.MuteList
+
+	Events      *Events
+	followedPks []string
+	feedId      string
+	sync        syncJob
+	pages       feedPages
+
+	subMu     sync.Mutex
+	feedSubs  []relays.SubHandle
+	notifySub relays.SubHandle
+
+	// Guards the config fields the client changes in the background, the
+	// read notifications and when deletions were synced
+	configMu      sync.Mutex
+	deletionsFrom nostr.Timestamp
+
+	// Pubkeys whose NIP-05 identifier is being checked
+	nip05Mu     sync.Mutex
+	nip05Checks map[string]bool
+}
+
+func New(cfg *config.Config, sink EventSink) *Client {
+	return &Client{
+		Config:      cfg,
+		Pool:        relays.NewPool(),
+		DB:          storage.NewDB(),
+		Mutes:       domain.NewMuteList(),
+		Events:      NewEvents(sink),
+		followedPks: []string{},
+		nip05Checks: map[string]bool{},
+	}
+}
+
+func (c *Client) BeginSubscriptions() {
+	c.LoadMuteList()
+	c.RefreshContactProfiles()
+	c.SubscribeToFeed(c.feedId, true)
+	c.SubscribeToNotifications()
+}
+
+func (c *Client) DumpEvents() {
+	c.DB.DumpEvents()
+}
+
+func (c *Client) RefreshContactProfiles() {
+	log.Debug().Msg("Refreshing Contact Profiles")
+	c.LoadContactList()
+
+	chks := domain.ChunkSlice(c.followedPks, QUERY_SIZE)
+	for _, chk := range chks {
+		c.GetMetadataEvents(chk)
+	}
+}
+
+// LoadContactList fetches our contact list and remembers who we follow
+func (c *Client) LoadContactList() []string {
+	c.followedPks = c.GetContactList(c.Config.Pubkey)
+	return c.followedPks
+}
+
+func (c *Client) RefreshFeed(repost bool) {
+	err := c.SubscribeToFeed(c.feedId, repost)
+	if err != nil {
+		log.Error().Msgf("Could not refresh feed %s: %s", c.feedId, err.Error())
+	}
+}
+
+func (c *Client) RefreshFeedReset() {
+	log.Debug().Msg("Resetting feed")
+	c.RefreshFeed(true)
+	c.SubscribeToNotifications()
+}
+
+func (c *Client) PkToNpub(pk string) (string, error) {
+	npub, err := nip19.EncodePublicKey(pk)
+	return npub, err
+}
+
+func (c *Client) GetContactList(pk string) []string {
+	log.Debug().Msgf("Getting contact list for %s", pk)
+
+	pks := []string{}
+	if pk == "" {
+		return pks
+	}
+
+	ev := c.getLatestContactList(pk)
+	if ev == nil {
+		return pks
+	}
+	pks = domain.ContactPubkeys(ev.Tags)
+
+	if pk == c.Config.Pubkey {
+		if domain.IsSuspiciousContactCount(len(pks), c.Config.LastContactCount) {
+			log.Warn().Msgf("Contact list has %d entries, last known %d", len(pks), c.Config.LastContactCount)
+		} else {
+			c.setLastContactCount(len(pks))
+		}
+	}
+
+	return pks
+}
+
+func (c *Client) GetMetadataEvents(pks []string) {
+	if len(pks) == 0 {
+		log.Warn().Msg("Getting metadata events called with no contacts!")
+		return
+	}
+	log.Debug().Msgf("Getting metadata events for %d keys: %s", len(pks), pks)
+
+	ch := make(chan *nostr.Event)
+	go func() {
+		for ev := range ch {
+			c.addMetadataEvent(ev)
+		}
+	}()
+
+	c.Pool.QuerySync(&nostr.Filter{
+		Authors: pks,
+		Kinds: []int{
+			nostr.KindSetMetadata,
+		},
+		Limit: len(pks),
+	}, ch)
+}
+
+// addMetadataEvent caches a kind-0 event and the profile built from it.
+// Versions older than the one cached leave the profile alone.
+func (c *Client) addMetadataEvent(ev *nostr.Event) *domain.Profile {
+	if !c.DB.AddEvent(ev.ID, ev) {
+		if existing := c.DB.GetProfile(ev.PubKey); existing != nil {
+			existing.Following = domain.Contains(c.followedPks, ev.PubKey)
+			if existing.Following {
+				go c.Events.Metadata(existing)
+			}
+			c.checkNip05(existing)
+			return existing
+		}
+		ev = c.DB.GetReplaceableEvent(ev.PubKey, nostr.KindSetMetadata, "")
+	}
+	cm, err := domain.GetContentMeta(ev)
+	if err != nil {
+		log.Error().Msgf("Error parsing metadata for event %s: %s", ev.ID, err.Error())
+		return nil
+	}
+	npub, err := c.PkToNpub(ev.PubKey)
+	if err != nil {
+		log.Error().Msgf("Error converting PK to NPUB for event %s: %s", ev.ID, err.Error())
+		return nil
+	}
+
+	profile := domain.Profile{
+		Pk:        ev.PubKey,
+		Following: domain.Contains(c.followedPks, ev.PubKey),
+		Meta:      *cm,
+		Npub:      npub,
+	}
+
+	// Keep the NIP-05 result while the identifier is unchanged
+	if existing := c.DB.GetProfile(ev.PubKey); existing != nil && existing.Meta.NIP05 == cm.NIP05 {
+		profile.Nip05Verified = existing.Nip05Verified
+		profile.Nip05CheckedAt = existing.Nip05CheckedAt
+		profile.Relays = existing.Relays
+	}
+
+	c.DB.AddProfile(ev.PubKey, &profile) // Overwrite if existing
+
+	if profile.Following {
+		go c.Events.Metadata(&profile)
+	}
+	c.checkNip05(&profile)
+	return &profile
+}
+
+func (c *Client) GetTaggedProfiles(parentEvent string) []*domain.Profile {
+	cachedProfiles := []*domain.Profile{}
+	missingProfiles := []string{}
+	dups := []string{}
+
+	ev := c.DB.GetEvent(parentEvent)
+	if ev != nil {
+		var pTags []nostr.Tag = nostr.Tags.GetAll(ev.Tags, []string{"p"})
+		for a := 0; a < len(pTags); a++ {
+			pk := pTags[a].Value()
+			if !domain.Contains(dups, pk) {
+				dups = append(dups, pk)
+				profile := c.DB.GetProfile(pk)
+				if profile != nil {
+					cachedProfiles = append(cachedProfiles, profile)
+				} else {
+					missingProfiles = append(missingProfiles, pk)
+				}
+			}
+		}
+
+		if len(missingProfiles) > 0 {
+			c.GetMetadataEvents(missingProfiles)
+			for a := 0; a < len(missingProfiles); a++ {
+				pk := missingProfiles[a]
+				profile := c.DB.GetProfile(pk)
+				if profile != nil {
+					cachedProfiles = append(cachedProfiles, profile)
+				}
+			}
+		}
+	} else {
+		log.Debug().Msgf("GetTaggedProfiles called for parent event %s (not cached)", parentEvent)
+	}
+
+	return cachedProfiles
+}
+
+func (c *Client) GetTaggedEvents(parentEvent string) []*nostr.Event {
+	cachedEvents := []*nostr.Event{}
+	missingEvents := []string{}
+
+	ev := c.DB.GetEvent(parentEvent)
+	if ev != nil {
+		var eTags []nostr.Tag = nostr.Tags.GetAll(ev.Tags, []string{"e"})
+		for a := 0; a < len(eTags); a++ {
+			evId := eTags[a].Value()
+			event := c.DB.GetEvent(evId)
+			if event != nil {
+				cachedEvents = append(cachedEvents, event)
+			} else {
+				missingEvents = append(missingEvents, evId)
+			}
+		}
+
+		if len(missingEvents) > 0 {
+			c.GetTextNotesByEventIds(missingEvents)
+			for a := 0; a < len(missingEvents); a++ {
+				evId := missingEvents[a]
+				event := c.DB.GetEvent(evId)
+				if event != nil {
+					cachedEvents = append(cachedEvents, event)
+				}
+			}
+		}
+	}
+
+	return cachedEvents
+}
+
+func (c *Client) GetContactProfile(pk string) (*domain.Profile, error) {
+	if domain.IsNip05Identifier(pk) {
+		return c.LookupNip05(pk)
+	}
+	hints := []string{}
+	if strings.HasPrefix(pk, "npub") || strings.HasPrefix(pk, "nprofile") {
+		val, err := c.Nip19Decode(pk)
+		if err != nil {
+			return nil, err
+		}
+		pk = val.PubKey
+		hints = val.Relays
+	}
+	return c.getProfile(pk, hints), nil
+}
+
+// getProfile returns the cached profile for a hex pubkey, fetching it first
+// if need be, from the relay hints as well as ours. A blank profile comes
+// back if none is found.
+func (c *Client) getProfile(pk string, hints []string) *domain.Profile {
+	if c.DB.HasProfile(pk) {
+		log.Trace().Msgf("GetContactProfile for PK %s (cache)", pk)
+		return c.DB.GetProfile(pk)
+	}
+	log.Trace().Msgf("GetContactProfile for PK %s (query)", pk)
+	if len(hints) > 0 {
+		events := c.Pool.QueryWithHints(&nostr.Filter{
+			Authors: []string{pk},
+			Kinds:   []int{nostr.KindSetMetadata},
+		}, hints)
+		for _, ev := range events {
+			c.addMetadataEvent(ev)
+		}
+	} else {
+		c.GetMetadataEvents([]string{pk})
+	}
+	if c.DB.HasProfile(pk) {
+		return c.DB.GetProfile(pk)
+	}
+	npub, _ := c.PkToNpub(pk)
+	return &domain.Profile{
+		Pk:        pk,
+		Following: false,
+		Meta:      domain.ProfileMetadata{},
+		Npub:      npub,
+		Relays:    nil,
+	}
+}
+
+func (c *Client) GetReadableRelays() []*string {
+	rs := []*string{}
+	for _, r := range c.Pool.Relays() {
+		if r.Enabled && r.Read && (r.Connected()) {
+			rs = append(rs, &r.Url)
+		}
+	}
+	return rs
+}
+
+func (c *Client) GetWritableRelays() []*string {
+	rs := []*string{}
+	for _, r := range c.Pool.Relays() {
+		if r.Enabled && r.Write && (r.Connected()) {
+			rs = append(rs, &r.Url)
+		}
+	}
+	return rs
+}
+
+func (c *Client) GetRelays() []*relays.Relay {
+	return c.Config.Relays
+}
+
+func (c *Client) SetRelays(r []*relays.Relay) {
+	c.Pool.DisconnectAll()
+	c.Pool.RemoveAll()
+
+	c.Pool.AddAll(r)
+	c.Config.Relays = r
+	c.pages.forget()
+
+	err := c.Config.Save()
+	if err != nil {
+		log.Error().Msgf("Error saving config file: %s", err.Error())
+		return
+	}
+
+	c.RefreshContactProfiles()
+	go c.RefreshFeed(false)
+}
+
+func (c *Client) GetTextNotesForPubkeys(pks []string, postEvent string, repost bool) error {
+	log.Debug().Msgf("Getting text events for pks...(%d)", len(pks))
+
+	if len(pks) == 0 {
+		return nil
+	}
+
+	ch := make(chan *nostr.Event)
+	go func() {
+		for ev := range ch {
+			existingEvent := c.DB.GetEvent(ev.ID)
+			c.DB.AddEvent(ev.ID, ev)
+			if existingEvent == nil || repost {
+				c.emitNote(postEvent, ev)
+			}
+		}
+	}()
+
+	c.Pool.QuerySync(&nostr.Filter{
+		Authors: pks,
+		Kinds:   []int{nostr.KindTextNote, nostr.KindBoost},
+		Limit:   100,
+	}, ch)
+
+	return nil
+}
+
+// SubscribeToFeedForPubkeys follows the notes and deletions of pks. Stored
+// notes go out as EV_FOLLOW_EVENT_NOTE and live ones as EV_REFRESH_NOTE;
+// with repost those already cached are sent again.
+func (c *Client) SubscribeToFeedForPubkeys(pks []string, repost bool) {
+	if len(pks) == 0 {
+		return
+	}
+	since := nostr.Now() - SECS_6H
+	filter := nostr.Filter{
+		Authors: pks,
+		Kinds: []int{
+			nostr.KindTextNote,
+			nostr.KindBoost,
+		},
+		Since: &since,
+	}
+	handle := c.Pool.Subscribe(relays.SUB_FEED, filter, func(ev *nostr.Event, live bool) {
+		existingEvent := c.DB.GetEvent(ev.ID)
+		c.DB.AddEvent(ev.ID, ev)
+		if existingEvent == nil || repost {
+			if live {
+				c.emitNote(EV_REFRESH_NOTE, ev)
+			} else {
+				c.emitNote(EV_FOLLOW_EVENT_NOTE, ev)
+			}
+		}
+	})
+
+	c.subMu.Lock()
+	c.feedSubs = append(c.feedSubs, handle, c.subscribeToDeletions(pks))
+	c.subMu.Unlock()
+}
+
+// unsubscribeFeed tears down the feed's subscriptions before it is
+// subscribed again
+func (c *Client) unsubscribeFeed() {
+	c.subMu.Lock()
+	handles := c.feedSubs
+	c.feedSubs = nil
+	c.subMu.Unlock()
+	c.Pool.Unsubscribe(handles...)
+}
+
+func (c *Client) GetTextNotesByEventIds(ids []string) []*nostr.Event {
+	log.Debug().Msgf("GetTextNotesByEventIds: %s", ids)
+	events := []*nostr.Event{}
+	if len(ids) == 0 {
+		return events
+	}
+
+	ch := make(chan *nostr.Event)
+	go func() {
+		for ev := range ch {
+			c.DB.AddEvent(ev.ID, ev)
+			events = append(events, ev)
+		}
+	}()
+	c.Pool.QuerySync(&nostr.Filter{
+		IDs: ids,
+		Kinds: []int{
+			nostr.KindTextNote,
+			nostr.KindBoost,
+		},
+	}, ch)
+
+	log.Debug().Msgf("GetTextNotesByEventIds returning %d events", len(events))
+	return events
+}
+
+func (c *Client) PostEvent(kind int, tags nostr.Tags, content string) *nostr.Event {
+	if kind == nostr.KindTextNote {
+		tags = c.markReplyTags(tags)
+		tags = c.mentionTags(content, tags)
+	}
+	ev := c.signAndPublish(kind, tags, content)
+	c.Events.RefreshNote(ev)
+	return ev
+}
+
+// signAndPublish signs a new event and sends it to all write relays
+func (c *Client) signAndPublish(kind int, tags nostr.Tags, content string) *nostr.Event {
+	ev := nostr.Event{
+		PubKey:    c.Config.Pubkey,
+		CreatedAt: nostr.Now(),
+		Kind:      kind,
+		Tags:      tags,
+		Content:   content,
+	}
+	ev.Sign(c.Config.PrivKeyHex)
+
+	c.publish(&ev)
+	c.DB.AddEvent(ev.ID, &ev)
+	return &ev
+}
+
+// publish sends a signed event to our write relays, returning how many took
+// it
+func (c *Client) publish(ev *nostr.Event) int {
+	accepted := 0
+	for _, r := range c.Pool.Relays() {
+		if r.Enabled && r.Write {
+			status, _ := r.Publish(context.Background(), *ev)
+			if status == nostr.PublishStatusSucceeded {
+				c.Pool.RecordSeen(ev.ID, r.Url)
+				accepted++
+			}
+			log.Info().Msgf("Published %s to relay %s", ev.ID, r.Url)
+		}
+	}
+	return accepted
+}
+
+// publishTo sends a signed event to the given write relays, returning the
+// ones that took it
+func (c *Client) publishTo(ev *nostr.Event, urls []string) []string {
+	accepted := []string{}
+	for _, url := range urls {
+		r := c.Pool.GetRelayByUrl(url)
+		if r == nil || !r.Enabled || !r.Write {
+			continue
+		}
+		status, _ := r.Publish(context.Background(), *ev)
+		if status == nostr.PublishStatusSucceeded {
+			c.Pool.RecordSeen(ev.ID, r.Url)
+			accepted = append(accepted, r.Url)
+		}
+		log.Info().Msgf("Published %s to %s", ev.ID, r.Url)
+	}
+	return accepted
+}
+
+// Forward stores an event someone else signed, such as one written to the
+// local relay, and publishes it to our write relays in the background.
+// Deletions are applied to the cache as well. An error means the event was
+// not stored.
+func (c *Client) Forward(ev *nostr.Event) error {
+	if err := c.Pool.Verify(ev); err != nil {
+		return err
+	}
+	if !c.DB.AddEvent(ev.ID, ev) && !c.DB.HasEvent(ev.ID) {
+		return errors.New("Event is deleted or older than the version we hold")
+	}
+	c.applyDeletion(ev)
+	go func() {
+		accepted := c.publish(ev)
+		log.Debug().Msgf("Forwarded %s to %d relays", ev.ID, accepted)
+	}()
+	return nil
+}
+
+func (c *Client) PublishContentToSelectedRelays(kind int, content string, ts [][]string, relays []string) {
+	tags := nostr.Tags{}
+
+	for _, tag := range ts {
+		tags = append(tags, nostr.Tag(tag))
+	}
+	if kind == nostr.KindTextNote {
+		tags = c.markReplyTags(tags)
+		tags = c.mentionTags(content, tags)
+	}
+
+	ev := nostr.Event{
+		PubKey:    c.Config.Pubkey,
+		CreatedAt: nostr.Now(),
+		Kind:      kind,
+		Tags:      tags,
+		Content:   content,
+	}
+	ev.Sign(c.Config.PrivKeyHex)
+
+	c.publishTo(&ev, relays)
+	c.Events.RefreshNote(&ev)
+}
+
+func (c *Client) FollowContact(pk []string) (*domain.ContactListDiff, error) {
+	return c.followContact(pk, false)
+}
+
+// FollowContactConfirmed follows even when no contact list was found or it
+// looks truncated, once the user has confirmed starting from it
+func (c *Client) FollowContactConfirmed(pk []string) (*domain.ContactListDiff, error) {
+	return c.followContact(pk, true)
+}
+
+func (c *Client) followContact(pk []string, force bool) (*domain.ContactListDiff, error) {
+	return c.updateContactList(func(draft *nostr.Event) {
+		for _, p := range pk {
+			draft.Tags = draft.Tags.AppendUnique(nostr.Tag{"p", p})
+		}
+	}, force)
+}
+
+func (c *Client) UnfollowContact(pk string) (*domain.ContactListDiff, error) {
+	return c.updateContactList(func(draft *nostr.Event) {
+		draft.Tags = draft.Tags.FilterOut([]string{"p", pk})
+	}, false)
+}
+
+func (c *Client) GetMyPubkey() string {
+	return c.Config.Pubkey
+}
+
+func (c *Client) SaveConfigDark(dark bool) {
+	c.Config.Dark = dark
+	err := c.Config.Save()
+	if err != nil {
+		log.Err(err)
+		return
+	}
+}
+
+func (c *Client) SetLoginWithPrivKey(keypin []string) error {
+	var err error
+	var cipher []byte
+
+	if len(keypin) != 2 {
+		return errors.New("Input error: expected key and PIN")
+	}
+	key := keypin[0]
+	pin := keypin[1]
+
+	if strings.HasPrefix(key, "nsec") {
+		val, e := c.Nip19Decode(key)
+		if e != nil {
+			return e
+		}
+		key = val.PrivKey
+	}
+
+	if pin == "" {
+		c.Config.Privkey = key
+	} else {
+		cipher, err = crypto.Encrypt([]byte(key), pin)
+		if err != nil {
+			return err
+		}
+		c.Config.Privkey = "ENC:" + b64.StdEncoding.EncodeToString([]byte(cipher))
+	}
+
+	c.Config.Pubkey, err = nostr.GetPublicKey(key)
+	c.Config.PrivKeyHex = key
+	if err != nil {
+		return err
+	}
+	c.Config.Save()
+	c.Events.PkChange(c.Config.Pubkey)
+
+	if len(c.Config.Relays) == 0 {
+		// Add some default relays
+		defaults := []*relays.Relay{}
+		addrs := []string{
+			"wss://nos.lol",
+			"wss://relay.damus.io",
+			"wss://relay.snort.social",
+			"wss://nostr.mom",
+		}
+
+		for _, addr := range addrs {
+			defaults = append(defaults, &relays.Relay{
+				Url:     addr,
+				Read:    true,
+				Write:   true,
+				Enabled: true,
+			})
+		}
+		c.SetRelays(defaults)
+	}
+
+	return nil
+}
+
+func (c *Client) LoginWithPin(pin string) error {
+	log.Debug().Msg("PIN login called")
+	parts := strings.SplitAfter(c.Config.Privkey, "ENC:")
+	if len(parts) != 2 {
+		return errors.New("Private key does not appear to be encrypted")
+	}
+	dec64, err := b64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		log.Err(err)
+		return errors.New("Error decoding the private key")
+	}
+
+	key, err := crypto.Decrypt(dec64, pin)
+	if err != nil {
+		return errors.New("Wrong PIN")
+	}
+	c.Config.PrivKeyHex = string(key)
+	c.Config.Pubkey, err = nostr.GetPublicKey(c.Config.PrivKeyHex)
+	c.Events.PkChange(c.Config.Pubkey)
+
+	log.Info().Msgf("PIN login success for %s", c.Config.Pubkey)
+	return nil
+}
+
+func (c *Client) GenerateKeys() (*map[string]string, error) {
+	log.Debug().Msg("Generating new keys")
+	keyDetail := make(map[string]string)
+
+	key := nostr.GeneratePrivateKey()
+	pk, _ := nostr.GetPublicKey(key)
+
+	keyDetail["key"] = key
+	keyDetail["pk"] = pk
+	return &keyDetail, nil
+}
+
+func (c *Client) SaveNewKeys(creds map[string]string) error {
+	log.Info().Msg("Saving new account details...")
+	key := creds["key"]
+	name := creds["name"]
+	displayName := creds["displayName"]
+	pin := creds["pin"]
+
+	c.SetLoginWithPrivKey([]string{key, pin})
+
+	// Set profile with this name/display name
+	meta := domain.ProfileMetadata{
+		Name:        name,
+		About:       "",
+		Picture:     "",
+		NIP05:       "",
+		DisplayName: displayName,
+		Lud06:       "",
+		Lud16:       "",
+		Banner:      "",
+		Website:     "",
+	}
+
+	return c.SaveProfile(meta)
+}
+
+func (c *Client) SaveProfile(metadata domain.ProfileMetadata) error {
+	log.Debug().Msg("Saving profile")
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		log.Err(err)
+		return err
+	}
+	c.PostEvent(nostr.KindSetMetadata, nostr.Tags{}, string(content))
+	return nil
+}
+
+// GetRelayStatus counts the connected relays, the REQs open on them, the
+// subscriptions they serve by purpose and those they had no room for
+func (c *Client) GetRelayStatus() RelayStatus {
+	readable := c.GetReadableRelays()
+	writable := c.GetWritableRelays()
+	numSubs, dropped := 0, 0
+	for _, url := range readable {
+		numSubs += c.Pool.SubCount(*url)
+		dropped += c.Pool.SubsDropped(*url)
+	}
+	return RelayStatus{
+		Readable: len(readable),
+		Writable: len(writable),
+		Subs:     numSubs,
+		Purposes: c.Pool.SubPurposes(),
+		Dropped:  dropped,
+	}
+}
+
+func (c *Client) CheckRelays() {
+	c.Events.RelayStatus(c.GetRelayStatus())
+}
+
+func (c *Client) PingTimer() {
+	c.Events.Timer(time.Now().UnixMilli())
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/config"
+	"testing"
+)
+
+// newTestClient is a headless client with a fresh key and no relays, so
+// lists are read from and published to the cache only. Config and archives
+// go to a temporary directory.
+func newTestClient(t *testing.T) (*Client, *Recorder) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cfg := config.NewConfig()
+	cfg.PrivKeyHex = nostr.GeneratePrivateKey()
+	pk, err := nostr.GetPublicKey(cfg.PrivKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Pubkey = pk
+	rec := NewRecorder()
+	return New(cfg, rec), rec
+}
+
+// addOwnEvent signs an event with the client's key and caches it, as if a
+// relay had sent it
+func addOwnEvent(t *testing.T, c *Client, kind int, tags nostr.Tags, content string, at nostr.Timestamp) *nostr.Event {
+	t.Helper()
+	ev := &nostr.Event{PubKey: c.Config.Pubkey, CreatedAt: at, Kind: kind, Tags: tags, Content: content}
+	if err := ev.Sign(c.Config.PrivKeyHex); err != nil {
+		t.Fatal(err)
+	}
+	c.DB.AddEvent(ev.ID, ev)
+	return ev
+}
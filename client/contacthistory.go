@@ -0,0 +1,164 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func (c *Client) contactArchiveDir() string {
+	return filepath.Join(c.Config.Dir(), "contacts", c.Config.Pubkey)
+}
+
+// archiveContactList keeps a copy of every version of our contact list we
+// come across, named by timestamp so the directory lists in order
+func (c *Client) archiveContactList(ev *nostr.Event) {
+	if ev == nil || ev.Kind != nostr.KindContactList || ev.PubKey != c.Config.Pubkey {
+		return
+	}
+
+	dir := c.contactArchiveDir()
+	path := filepath.Join(dir, fmt.Sprintf("%d-%s.json", ev.CreatedAt, ev.ID))
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		log.Error().Msgf("Could not create contact archive %s: %s", dir, err.Error())
+		return
+	}
+	err = os.WriteFile(path, []byte(ev.String()+"\n"), 0644)
+	if err != nil {
+		log.Error().Msgf("Could not archive contact list %s: %s", ev.ID, err.Error())
+		return
+	}
+	log.Debug().Msgf("Archived contact list version %s (%d contacts)", ev.ID, len(domain.ContactPubkeys(ev.Tags)))
+}
+
+// loadContactArchive returns the archived contact lists, newest first
+func (c *Client) loadContactArchive() []*nostr.Event {
+	events := []*nostr.Event{}
+	files, err := os.ReadDir(c.contactArchiveDir())
+	if err != nil {
+		return events
+	}
+
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		buffer, err := os.ReadFile(filepath.Join(c.contactArchiveDir(), f.Name()))
+		if err != nil {
+			log.Err(err)
+			continue
+		}
+		var ev nostr.Event
+		err = json.Unmarshal(buffer, &ev)
+		if err != nil {
+			log.Error().Msgf("Bad contact archive file %s: %s", f.Name(), err.Error())
+			continue
+		}
+		events = append(events, &ev)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt > events[j].CreatedAt
+	})
+	return events
+}
+
+func (c *Client) getArchivedContactList(id string) (*nostr.Event, error) {
+	for _, ev := range c.loadContactArchive() {
+		if ev.ID == id {
+			return ev, nil
+		}
+	}
+	return nil, errors.New("No archived contact list " + id)
+}
+
+func (c *Client) GetContactListVersions() []*domain.ContactListVersion {
+	versions := []*domain.ContactListVersion{}
+	for _, ev := range c.loadContactArchive() {
+		versions = append(versions, &domain.ContactListVersion{
+			Id:        ev.ID,
+			CreatedAt: ev.CreatedAt,
+			Count:     len(domain.ContactPubkeys(ev.Tags)),
+		})
+	}
+	return versions
+}
+
+func (c *Client) DiffContactListVersions(fromId string, toId string) (*domain.ContactListDiff, error) {
+	from, err := c.getArchivedContactList(fromId)
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.getArchivedContactList(toId)
+	if err != nil {
+		return nil, err
+	}
+	return domain.DiffContacts(domain.ContactPubkeys(from.Tags), domain.ContactPubkeys(to.Tags)), nil
+}
+
+// RestoreContactListVersion republishes the tags and content of an archived
+// contact list as a new version
+func (c *Client) RestoreContactListVersion(id string) (*domain.ContactListDiff, error) {
+	version, err := c.getArchivedContactList(id)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Msgf("Restoring contact list version %s from %s", id, version.CreatedAt.Time())
+
+	// Restoring is how a wiped list is fixed, so skip the size check
+	return c.updateContactList(func(draft *nostr.Event) {
+		draft.Tags = nostr.Tags{}
+		for _, tag := range version.Tags {
+			draft.Tags = append(draft.Tags, append(nostr.Tag{}, tag...))
+		}
+		draft.Content = version.Content
+	}, true)
+}
+
+// SaveContacts makes sure the current contact list is in the archive and
+// returns where the archive lives
+func (c *Client) SaveContacts() (*string, error) {
+	ev := c.getLatestContactList(c.Config.Pubkey)
+	if ev == nil {
+		return nil, errors.New("No contact list found")
+	}
+	path := c.contactArchiveDir()
+	return &path, nil
+}
+
+// RestoreContacts republishes the newest archived contact list that is not
+// itself suspiciously small compared to the largest one archived
+func (c *Client) RestoreContacts() (*string, error) {
+	archive := c.loadContactArchive()
+	largest := 0
+	for _, ev := range archive {
+		if n := len(domain.ContactPubkeys(ev.Tags)); n > largest {
+			largest = n
+		}
+	}
+
+	for _, ev := range archive {
+		n := len(domain.ContactPubkeys(ev.Tags))
+		if n == 0 || domain.IsSuspiciousContactCount(n, largest) {
+			continue
+		}
+		_, err := c.RestoreContactListVersion(ev.ID)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(c.contactArchiveDir(), fmt.Sprintf("%d-%s.json", ev.CreatedAt, ev.ID))
+		return &path, nil
+	}
+	return nil, errors.New("No contacts in archive. Changes not published")
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"reflect"
+	"testing"
+)
+
+func TestContactListArchivedOnce(t *testing.T) {
+	c, _ := newTestClient(t)
+	ev := addOwnEvent(t, c, nostr.KindContactList, nostr.Tags{{"p", "aa"}}, "", nostr.Now())
+	c.archiveContactList(ev)
+	c.archiveContactList(ev)
+	// Nor are other people's lists archived
+	c.archiveContactList(signedBy(t, nostr.GeneratePrivateKey(), nostr.KindContactList, nostr.Tags{{"p", "bb"}}, ""))
+
+	versions := c.GetContactListVersions()
+	if len(versions) != 1 {
+		t.Fatalf("%d versions archived, want 1", len(versions))
+	}
+	if versions[0].Id != ev.ID || versions[0].Count != 1 {
+		t.Errorf("Version %+v, want %s with 1 contact", versions[0], ev.ID)
+	}
+
+	// Publishing a change archives the new version
+	if _, err := c.FollowContact([]string{"cc"}); err != nil {
+		t.Fatal(err)
+	}
+	if versions := c.GetContactListVersions(); len(versions) != 2 || versions[0].Count != 2 {
+		t.Errorf("Versions %+v, want the new one of 2 contacts first", versions)
+	}
+}
+
+func TestDiffContactListVersions(t *testing.T) {
+	c, _ := newTestClient(t)
+	now := nostr.Now()
+	older := addOwnEvent(t, c, nostr.KindContactList, nostr.Tags{{"p", "aa"}, {"p", "bb"}}, "", now-20)
+	newer := addOwnEvent(t, c, nostr.KindContactList, nostr.Tags{{"p", "bb"}, {"p", "cc"}}, "", now-10)
+	c.archiveContactList(older)
+	c.archiveContactList(newer)
+
+	diff, err := c.DiffContactListVersions(older.ID, newer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff.Added, []string{"cc"}) || !reflect.DeepEqual(diff.Removed, []string{"aa"}) {
+		t.Errorf("Diff %+v, want cc added and aa removed", diff)
+	}
+	if _, err := c.DiffContactListVersions(older.ID, "missing"); err == nil {
+		t.Error("Diffed against a version that is not archived")
+	}
+}
+
+func TestRestoreContactListVersion(t *testing.T) {
+	c, _ := newTestClient(t)
+	now := nostr.Now()
+	tags := nostr.Tags{{"p", "aa", "wss://relay.example", "alice"}, {"p", "bb"}, {"t", "nostr"}}
+	relays := `{"wss://relay.example":{"read":true,"write":true}}`
+	old := addOwnEvent(t, c, nostr.KindContactList, tags, relays, now-20)
+	wiped := addOwnEvent(t, c, nostr.KindContactList, nostr.Tags{}, "", now-10)
+	c.archiveContactList(old)
+	c.archiveContactList(wiped)
+
+	diff, err := c.RestoreContactListVersion(old.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 2 || len(diff.Removed) != 0 {
+		t.Errorf("Diff %+v, want both contacts added back", diff)
+	}
+	ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, nostr.KindContactList, "")
+	if ev.ID == old.ID || ev.ID == wiped.ID {
+		t.Fatal("Restored version not published")
+	}
+	if !reflect.DeepEqual(ev.Tags, tags) || ev.Content != relays {
+		t.Errorf("Restored %v %q, want the old tags and content", ev.Tags, ev.Content)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+)
+
+// ErrNoContactList is returned when no contact list could be fetched to
+// change. Publishing a new one would replace any list the relays failed to
+// return, so the user has to confirm it.
+var ErrNoContactList = errors.New("No contact list found on the relays, a new one would replace any you have")
+
+// getLatestContactList returns the newest kind-3 event for pk across all
+// relays and the cache
+func (c *Client) getLatestContactList(pk string) *nostr.Event {
+	filter := nostr.Filter{
+		Authors: []string{pk},
+		Kinds:   []int{nostr.KindContactList},
+	}
+
+	for _, ev := range c.Pool.QueryAll(&filter) {
+		if ev.PubKey == c.Config.Pubkey {
+			c.archiveContactList(ev)
+		}
+		c.DB.AddEvent(ev.ID, ev)
+	}
+	return c.DB.GetReplaceableEvent(pk, nostr.KindContactList, "")
+}
+
+// updateContactList applies a change to a draft copy of the latest published
+// contact list, so every tag and the content not touched by the change are
+// kept. Unless forced, the change is refused if no list was found or the
+// list fetched looks truncated.
+func (c *Client) updateContactList(change func(draft *nostr.Event), force bool) (*domain.ContactListDiff, error) {
+	if c.Config.Pubkey == "" {
+		return nil, errors.New("Not logged in")
+	}
+
+	latest := c.getLatestContactList(c.Config.Pubkey)
+	if latest == nil && !force {
+		return nil, ErrNoContactList
+	}
+	draft := nostr.Event{
+		Kind: nostr.KindContactList,
+		Tags: nostr.Tags{},
+	}
+	if latest != nil {
+		for _, tag := range latest.Tags {
+			draft.Tags = append(draft.Tags, append(nostr.Tag{}, tag...))
+		}
+		draft.Content = latest.Content
+	}
+
+	before := domain.ContactPubkeys(draft.Tags)
+	if !force && domain.IsSuspiciousContactCount(len(before), c.Config.LastContactCount) {
+		return nil, fmt.Errorf("Contact list from relays has %d entries but %d were expected. Changes not published",
+			len(before), c.Config.LastContactCount)
+	}
+
+	change(&draft)
+	after := domain.ContactPubkeys(draft.Tags)
+	diff := domain.DiffContacts(before, after)
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && (latest == nil || draft.Content == latest.Content) {
+		log.Debug().Msg("Contact list unchanged, not publishing")
+		return diff, nil
+	}
+
+	ev := c.signAndPublish(nostr.KindContactList, draft.Tags, draft.Content)
+	c.archiveContactList(ev)
+	c.followedPks = after
+	c.setLastContactCount(len(after))
+	log.Info().Msgf("Published contact list: %d added, %d removed", len(diff.Added), len(diff.Removed))
+
+	c.Events.RefreshContacts()
+	return diff, nil
+}
+
+func (c *Client) setLastContactCount(count int) {
+	if count == c.Config.LastContactCount {
+		return
+	}
+	c.Config.LastContactCount = count
+	err := c.Config.Save()
+	if err != nil {
+		log.Error().Msgf("Error saving config file: %s", err.Error())
+	}
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"testing"
+)
+
+func TestFollowRefusesWithoutContactList(t *testing.T) {
+	c, _ := newTestClient(t)
+	if _, err := c.FollowContact([]string{"aa"}); !errors.Is(err, ErrNoContactList) {
+		t.Fatalf("got %v, want ErrNoContactList", err)
+	}
+	if ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, nostr.KindContactList, ""); ev != nil {
+		t.Fatalf("published %v", ev.Tags)
+	}
+
+	diff, err := c.FollowContactConfirmed([]string{"aa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.After != 1 {
+		t.Errorf("diff %+v", diff)
+	}
+}
+
+func TestFollowKeepsOtherTags(t *testing.T) {
+	c, _ := newTestClient(t)
+	addOwnEvent(t, c, nostr.KindContactList, nostr.Tags{{"p", "aa", "wss://relay.example.com", "alice"}, {"t", "nostr"}},
+		`{"wss://relay.example.com":{"read":true,"write":true}}`, nostr.Now()-10)
+
+	diff, err := c.FollowContact([]string{"bb", "aa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0] != "bb" || len(diff.Removed) != 0 || diff.After != 2 {
+		t.Errorf("diff %+v", diff)
+	}
+	ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, nostr.KindContactList, "")
+	if len(ev.Tags) != 3 || len(ev.Tags[0]) != 4 || ev.Tags[1][0] != "t" || ev.Content == "" {
+		t.Errorf("published %v %q", ev.Tags, ev.Content)
+	}
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+)
+
+// ParseContent tokenizes note content for display and fills in the profiles
+// and events it refers to
+func (c *Client) ParseContent(content string, tags [][]string) []*domain.ContentToken {
+	evTags := nostr.Tags{}
+	for _, tag := range tags {
+		evTags = append(evTags, nostr.Tag(tag))
+	}
+	tokens := domain.ParseContent(content, evTags)
+	c.resolveContentTokens(tokens)
+	return tokens
+}
+
+func (c *Client) resolveContentTokens(tokens []*domain.ContentToken) {
+	missing := []string{}
+	for _, token := range tokens {
+		if token.Entity == nil || token.Entity.Id == "" {
+			continue
+		}
+		if c.DB.GetEvent(token.Entity.Id) == nil && !domain.Contains(missing, token.Entity.Id) {
+			missing = append(missing, token.Entity.Id)
+		}
+	}
+	c.GetTextNotesByEventIds(missing)
+
+	for _, token := range tokens {
+		if token.Entity == nil {
+			continue
+		}
+		switch token.Entity.Type {
+		case "npub", "nprofile":
+			// #[n] tokens carry the tag's relay hint the same way
+			token.Profile = c.getProfile(token.Entity.PubKey, token.Entity.Relays)
+		case "note", "nevent":
+			ev := c.DB.GetEvent(token.Entity.Id)
+			if ev == nil && len(token.Entity.Relays) > 0 {
+				for _, found := range c.Pool.QueryWithHints(&nostr.Filter{IDs: []string{token.Entity.Id}}, token.Entity.Relays) {
+					c.DB.AddEvent(found.ID, found)
+					ev = found
+				}
+			}
+			token.Event = ev
+		case "naddr":
+			token.Event = c.getAddressedEvent(token.Entity)
+		}
+	}
+}
+
+// getAddressedEvent returns the newest version of the replaceable or
+// addressable event an naddr points at
+func (c *Client) getAddressedEvent(entity *domain.Nip19Entity) *nostr.Event {
+	if ev := c.DB.GetReplaceableEvent(entity.PubKey, entity.Kind, entity.Identifier); ev != nil {
+		return ev
+	}
+	filter := nostr.Filter{
+		Authors: []string{entity.PubKey},
+		Kinds:   []int{entity.Kind},
+	}
+	if domain.IsAddressableKind(entity.Kind) {
+		filter.Tags = nostr.TagMap{"d": []string{entity.Identifier}}
+	}
+	for _, ev := range c.Pool.QueryWithHints(&filter, entity.Relays) {
+		c.DB.AddEvent(ev.ID, ev)
+	}
+	return c.DB.GetReplaceableEvent(entity.PubKey, entity.Kind, entity.Identifier)
+}
+
+// mentionTags adds the tags NIP-27 expects for the nostr: mentions in content:
+// "p" for profiles and "q" quotes plus "e" mentions for events, along with the
+// author of the event quoted
+func (c *Client) mentionTags(content string, tags nostr.Tags) nostr.Tags {
+	addPubkey := func(pk string, hint string) {
+		if pk == "" || domain.HasTag(tags, "p", pk) {
+			return
+		}
+		tags = append(tags, nostr.Tag{"p", pk, hint})
+	}
+
+	for _, token := range domain.ParseContent(content, tags) {
+		if token.Type != domain.TOKEN_NOSTR {
+			continue
+		}
+		entity := token.Entity
+		hint := ""
+		if len(entity.Relays) > 0 {
+			hint = entity.Relays[0]
+		}
+
+		switch entity.Type {
+		case "npub", "nprofile":
+			addPubkey(entity.PubKey, hint)
+		case "note", "nevent":
+			if hint == "" {
+				hint = c.relayHint(entity.Id)
+			}
+			author := entity.PubKey
+			if ev := c.DB.GetEvent(entity.Id); ev != nil {
+				author = ev.PubKey
+			}
+			if !domain.HasTag(tags, "q", entity.Id) {
+				tags = append(tags, nostr.Tag{"q", entity.Id, hint})
+			}
+			if !domain.HasTag(tags, "e", entity.Id) {
+				tags = append(tags, nostr.Tag{"e", entity.Id, hint, "mention"})
+			}
+			addPubkey(author, "")
+		case "naddr":
+			if !domain.HasTag(tags, "q", token.Value) {
+				tags = append(tags, nostr.Tag{"q", token.Value, hint})
+			}
+			addPubkey(entity.PubKey, "")
+		}
+	}
+	return tags
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"greet/domain"
+	"testing"
+)
+
+func TestResolveContentTokens(t *testing.T) {
+	c, _ := newTestClient(t)
+	meta := addOwnEvent(t, c, nostr.KindSetMetadata, nostr.Tags{}, `{"name":"alice"}`, nostr.Now())
+	c.addMetadataEvent(meta)
+	article := addOwnEvent(t, c, domain.KIND_ARTICLE, nostr.Tags{{"d", "post"}}, "text", nostr.Now())
+	naddr, _ := nip19.EncodeEntity(c.Config.Pubkey, domain.KIND_ARTICLE, "post", []string{"wss://relay.example.com"})
+
+	tokens := c.ParseContent("cc #[0] on nostr:"+naddr, [][]string{{"p", c.Config.Pubkey, "wss://relay.example.com"}})
+	if len(tokens) != 4 {
+		t.Fatalf("got %d tokens", len(tokens))
+	}
+	if p := tokens[1].Profile; p == nil || p.Pk != c.Config.Pubkey || p.Meta.Name != "alice" {
+		t.Errorf("#[0] resolved to %+v", p)
+	}
+	if ev := tokens[3].Event; ev == nil || ev.ID != article.ID {
+		t.Errorf("naddr resolved to %v", ev)
+	}
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"greet/relays"
+	"strconv"
+	"strings"
+)
+
+// DeleteEvent publishes a NIP-09 deletion request for one of our events and
+// applies it locally straight away. An event that is not cached is fetched,
+// as the request needs its kind.
+func (c *Client) DeleteEvent(evId string) error {
+	target := c.DB.GetEvent(evId)
+	if target == nil {
+		for _, ev := range c.Pool.QueryAll(&nostr.Filter{IDs: []string{evId}, Authors: []string{c.Config.Pubkey}}) {
+			c.DB.AddEvent(ev.ID, ev)
+		}
+		target = c.DB.GetEvent(evId)
+	}
+	if target == nil {
+		return errors.New("Event not found, so its kind for the deletion is not known: " + evId)
+	}
+	if target.PubKey != c.Config.Pubkey {
+		return errors.New("Not deleting an event written by someone else: " + evId)
+	}
+	tags := nostr.Tags{{"e", evId}, {"k", strconv.Itoa(target.Kind)}}
+	if domain.IsReplaceableKind(target.Kind) || domain.IsAddressableKind(target.Kind) {
+		tags = append(tags, nostr.Tag{"a", domain.EventAddress(target)})
+	}
+
+	ev := c.signAndPublish(nostr.KindDeletion, tags, "Deletion request")
+	log.Info().Msgf("Delete %s requested with %s", evId, ev.ID)
+	c.applyDeletion(ev)
+	return nil
+}
+
+// applyDeletion removes what a kind-5 event deletes from the cache and tells
+// the frontend to drop it. References to other authors' events are ignored.
+func (c *Client) applyDeletion(ev *nostr.Event) {
+	if ev.Kind != nostr.KindDeletion {
+		return
+	}
+	removed := []string{}
+	for _, tag := range ev.Tags {
+		switch tag.Key() {
+		case "e":
+			if target := c.DB.GetEvent(tag.Value()); target != nil && target.PubKey != ev.PubKey {
+				continue
+			}
+			removed = append(removed, c.DB.AddDeletion(tag.Value(), ev)...)
+		case "a":
+			// kind:pubkey:d, and only the author may delete it
+			parts := strings.SplitN(tag.Value(), ":", 3)
+			if len(parts) != 3 || parts[1] != ev.PubKey {
+				continue
+			}
+			kind, err := strconv.Atoi(parts[0])
+			if err != nil {
+				continue
+			}
+			if domain.IsReplaceableKind(kind) {
+				parts[2] = ""
+			}
+			removed = append(removed, c.DB.AddDeletion(fmt.Sprintf("%d:%s:%s", kind, parts[1], parts[2]), ev)...)
+		}
+	}
+
+	if len(removed) > 0 {
+		log.Debug().Msgf("Deletion %s removed %d events", ev.ID, len(removed))
+		c.Events.EventDeleted(removed)
+	}
+}
+
+// subscribeToDeletions follows the deletion requests of the authors in the
+// feed so their deleted notes disappear
+func (c *Client) subscribeToDeletions(pks []string) relays.SubHandle {
+	since := c.deletionsSince(pks)
+	filter := nostr.Filter{
+		Authors: pks,
+		Kinds:   []int{nostr.KindDeletion},
+		Since:   &since,
+	}
+	return c.Pool.Subscribe(relays.SUB_DELETIONS, filter, func(ev *nostr.Event, live bool) {
+		c.applyDeletion(ev)
+	})
+}
+
+// deletionsSince is how far back to look for deletion requests from pks: to
+// when deletions were last synced, before this session, or to the oldest of
+// their events in the cache if that is older, as a deletion comes after what
+// it deletes. With neither it is the last 24 hours.
+func (c *Client) deletionsSince(pks []string) nostr.Timestamp {
+	c.configMu.Lock()
+	if c.deletionsFrom == 0 {
+		c.deletionsFrom = c.Config.DeletionsSynced
+		if c.deletionsFrom == 0 {
+			c.deletionsFrom = nostr.Now() - SECS_24H
+		}
+		c.Config.DeletionsSynced = nostr.Now()
+		if err := c.Config.Save(); err != nil {
+			log.Error().Msgf("Error saving config file: %s", err.Error())
+		}
+	}
+	since := c.deletionsFrom
+	c.configMu.Unlock()
+
+	for _, ev := range c.DB.QueryEvents(&nostr.Filter{Authors: pks}) {
+		if ev.CreatedAt < since {
+			since = ev.CreatedAt
+		}
+	}
+	return since
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+	"strconv"
+	"testing"
+)
+
+func TestDeleteEventTags(t *testing.T) {
+	c, _ := newTestClient(t)
+	article := addOwnEvent(t, c, domain.KIND_ARTICLE, nostr.Tags{{"d", "post"}}, "Text", nostr.Now())
+	if err := c.DeleteEvent(article.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deletions := c.DB.QueryEvents(&nostr.Filter{Kinds: []int{nostr.KindDeletion}})
+	if len(deletions) != 1 {
+		t.Fatalf("%d deletion requests, want 1", len(deletions))
+	}
+	tags := deletions[0].Tags
+	if !domain.HasTag(tags, "e", article.ID) {
+		t.Error("No e tag for the article")
+	}
+	if !domain.HasTag(tags, "k", strconv.Itoa(domain.KIND_ARTICLE)) {
+		t.Error("No k tag with the article's kind")
+	}
+	if !domain.HasTag(tags, "a", domain.EventAddress(article)) {
+		t.Error("No a tag with the article's address")
+	}
+}
+
+func TestDeleteEventRefused(t *testing.T) {
+	c, _ := newTestClient(t)
+	// Not cached and no relays to fetch it from, so the kind is unknown
+	if err := c.DeleteEvent("0000000000000000000000000000000000000000000000000000000000000000"); err == nil {
+		t.Error("Deleted an event of unknown kind")
+	}
+	other := signedBy(t, nostr.GeneratePrivateKey(), nostr.KindTextNote, nostr.Tags{}, "Theirs")
+	c.DB.AddEvent(other.ID, other)
+	if err := c.DeleteEvent(other.ID); err == nil {
+		t.Error("Deleted someone else's event")
+	}
+	if got := c.DB.QueryEvents(&nostr.Filter{Kinds: []int{nostr.KindDeletion}}); len(got) != 0 {
+		t.Errorf("%d deletion requests published, want none", len(got))
+	}
+}
+
+func TestDeletionsSince(t *testing.T) {
+	c, _ := newTestClient(t)
+	synced := nostr.Now() - 7*SECS_24H
+	c.Config.DeletionsSynced = synced
+	if since := c.deletionsSince([]string{c.Config.Pubkey}); since != synced {
+		t.Errorf("Since %d, want the last sync %d", since, synced)
+	}
+	if c.Config.DeletionsSynced < nostr.Now()-5 {
+		t.Error("Sync time not moved on")
+	}
+
+	// The whole session looks back as far, and further for older notes
+	old := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Old", synced-SECS_24H)
+	if since := c.deletionsSince([]string{c.Config.Pubkey}); since != old.CreatedAt {
+		t.Errorf("Since %d, want the oldest cached note %d", since, old.CreatedAt)
+	}
+	if since := c.deletionsSince([]string{"someone"}); since != synced {
+		t.Errorf("Since %d for another author, want the last sync %d", since, synced)
+	}
+}
+
+// Another user's deletion of the same note, before or after the author's,
+// neither replaces the author's nor deletes the note itself
+func TestDeletionsByTwoDeleters(t *testing.T) {
+	author, other := nostr.GeneratePrivateKey(), nostr.GeneratePrivateKey()
+	note := signedBy(t, author, nostr.KindTextNote, nostr.Tags{}, "Note")
+	byAuthor := signedBy(t, author, nostr.KindDeletion, nostr.Tags{{"e", note.ID}}, "")
+	byOther := signedBy(t, other, nostr.KindDeletion, nostr.Tags{{"e", note.ID}}, "")
+	byOther.CreatedAt = byAuthor.CreatedAt + 10
+	if err := byOther.Sign(other); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, order := range [][]*nostr.Event{{byAuthor, byOther}, {byOther, byAuthor}} {
+		c, _ := newTestClient(t)
+		for _, deletion := range order {
+			c.applyDeletion(deletion)
+		}
+		if c.DB.AddEvent(note.ID, note) || !c.DB.IsDeleted(note) {
+			t.Errorf("Note not deleted with deletions by %s then %s", order[0].PubKey[:8], order[1].PubKey[:8])
+		}
+	}
+
+	c, _ := newTestClient(t)
+	c.applyDeletion(byOther)
+	if !c.DB.AddEvent(note.ID, note) || c.DB.IsDeleted(note) {
+		t.Error("Note deleted by someone other than its author")
+	}
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/rs/zerolog/log"
+)
+
+// SendDirectMessage sends a NIP-04 encrypted direct message, the kind most
+// clients still read
+func (c *Client) SendDirectMessage(pk string, text string) (*nostr.Event, error) {
+	if c.Config.PrivKeyHex == "" {
+		return nil, errors.New("Private key not available")
+	}
+	if !nostr.IsValidPublicKeyHex(pk) {
+		return nil, errors.New("Not a valid public key: " + pk)
+	}
+
+	key, err := nip04.ComputeSharedSecret(pk, c.Config.PrivKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	content, err := nip04.Encrypt(text, key)
+	if err != nil {
+		return nil, err
+	}
+
+	ev := c.signAndPublish(nostr.KindEncryptedDirectMessage, nostr.Tags{{"p", pk}}, content)
+	log.Info().Msgf("Sent direct message %s to %s", ev.ID, pk)
+	return ev, nil
+}
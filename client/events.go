@@ -0,0 +1,178 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+	"sync"
+)
+
+// Events the client sends to the frontend
+const (
+	EV_METADATA          = "evMetadata"
+	EV_FOLLOW_EVENT_NOTE = "evFollowEventNote"
+	EV_REFRESH_NOTE      = "evRefreshNote"
+	EV_RELAY_STATUS      = "evRelayStatus"
+	EV_TIMER             = "evTimer"
+	EV_PK_CHANGE         = "evPkChange"
+	EV_REFRESH_CONTACTS  = "evRefreshContacts"
+	EV_NOTIFICATION      = "evNotification"
+	EV_EVENT_DELETED     = "evEventDeleted"
+	EV_ARTICLE           = "evArticle"
+	EV_LOGIN_DIALOG      = "evLoginDialog"
+	EV_PIN_DIALOG        = "evPinDialog"
+	EV_SYNC_PROGRESS     = "evSyncProgress"
+)
+
+// EventSink delivers client events to whatever is showing them: the desktop
+// window, or a Recorder when headless. A nil payload means the event has no
+// data.
+type EventSink interface {
+	Emit(name string, payload interface{})
+}
+
+type RelayStatus struct {
+	Readable int            `json:"readable"`
+	Writable int            `json:"writable"`
+	Subs     int            `json:"subs"`
+	Purposes map[string]int `json:"purposes"`
+	// Subscriptions some relay left out for want of room
+	Dropped int `json:"dropped"`
+}
+
+// Events wraps the sinks with one method per event so payloads are checked at
+// compile time. Every event goes to every sink.
+type Events struct {
+	mu    sync.RWMutex
+	sinks []EventSink
+}
+
+func NewEvents(sink EventSink) *Events {
+	return &Events{sinks: []EventSink{sink}}
+}
+
+// AddSink sends events to another sink as well, such as the local API
+func (e *Events) AddSink(sink EventSink) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sinks = append(e.sinks, sink)
+}
+
+func (e *Events) RemoveSink(sink EventSink) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, s := range e.sinks {
+		if s == sink {
+			e.sinks = append(e.sinks[:i], e.sinks[i+1:]...)
+			return
+		}
+	}
+}
+
+func (e *Events) emit(name string, payload interface{}) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, s := range e.sinks {
+		s.Emit(name, payload)
+	}
+}
+
+func (e *Events) Metadata(profile *domain.Profile) {
+	e.emit(EV_METADATA, profile)
+}
+
+// Note sends a note under the event name the caller asked for, which is
+// evRefreshNote or evFollowEventNote unless the frontend picks its own
+func (e *Events) Note(name string, ev *nostr.Event) {
+	e.emit(name, ev)
+}
+
+func (e *Events) RefreshNote(ev *nostr.Event) {
+	e.Note(EV_REFRESH_NOTE, ev)
+}
+
+func (e *Events) FollowEventNote(ev *nostr.Event) {
+	e.Note(EV_FOLLOW_EVENT_NOTE, ev)
+}
+
+func (e *Events) RelayStatus(status RelayStatus) {
+	e.emit(EV_RELAY_STATUS, status)
+}
+
+// Timer ticks with the time in milliseconds
+func (e *Events) Timer(ms int64) {
+	e.emit(EV_TIMER, ms)
+}
+
+func (e *Events) PkChange(pk string) {
+	e.emit(EV_PK_CHANGE, pk)
+}
+
+func (e *Events) RefreshContacts() {
+	e.emit(EV_REFRESH_CONTACTS, nil)
+}
+
+func (e *Events) Notification(n *domain.Notification) {
+	e.emit(EV_NOTIFICATION, n)
+}
+
+// EventDeleted lists the ids of the events a deletion removed
+func (e *Events) EventDeleted(ids []string) {
+	e.emit(EV_EVENT_DELETED, ids)
+}
+
+func (e *Events) Article(article *domain.Article) {
+	e.emit(EV_ARTICLE, article)
+}
+
+func (e *Events) LoginDialog() {
+	e.emit(EV_LOGIN_DIALOG, nil)
+}
+
+func (e *Events) PinDialog() {
+	e.emit(EV_PIN_DIALOG, nil)
+}
+
+func (e *Events) SyncProgress(progress SyncProgress) {
+	e.emit(EV_SYNC_PROGRESS, progress)
+}
+
+type RecordedEvent struct {
+	Name    string
+	Payload interface{}
+}
+
+// Recorder is an EventSink that keeps what it is sent in memory, for headless
+// use and for checking what the client emitted
+type Recorder struct {
+	mu     sync.Mutex
+	events []RecordedEvent
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{events: []RecordedEvent{}}
+}
+
+func (r *Recorder) Emit(name string, payload interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, RecordedEvent{Name: name, Payload: payload})
+}
+
+// Events returns what was recorded under name, or everything for a blank name
+func (r *Recorder) Events(name string) []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := []RecordedEvent{}
+	for _, ev := range r.events {
+		if name == "" || ev.Name == name {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = []RecordedEvent{}
+}
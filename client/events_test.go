@@ -0,0 +1,106 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+	"reflect"
+	"testing"
+)
+
+// signedBy signs an event with another key, as from another user
+func signedBy(t *testing.T, sk string, kind int, tags nostr.Tags, content string) *nostr.Event {
+	t.Helper()
+	pk, err := nostr.GetPublicKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := &nostr.Event{PubKey: pk, CreatedAt: nostr.Now(), Kind: kind, Tags: tags, Content: content}
+	if err := ev.Sign(sk); err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func TestRelayStatusEvent(t *testing.T) {
+	c, rec := newTestClient(t)
+	c.CheckRelays()
+
+	got := rec.Events(EV_RELAY_STATUS)
+	if len(got) != 1 {
+		t.Fatalf("%d relay status events, want 1", len(got))
+	}
+	status, ok := got[0].Payload.(RelayStatus)
+	if !ok {
+		t.Fatalf("Payload is %T, want RelayStatus", got[0].Payload)
+	}
+	if status.Readable != 0 || status.Writable != 0 || status.Subs != 0 || status.Dropped != 0 {
+		t.Errorf("Status %+v, want nothing connected", status)
+	}
+}
+
+func TestNotificationEvent(t *testing.T) {
+	c, rec := newTestClient(t)
+	ev := signedBy(t, nostr.GeneratePrivateKey(), nostr.KindTextNote, nostr.Tags{{"p", c.Config.Pubkey}}, "Hello")
+	c.onNotificationEvent(ev)
+	// The same event from a second relay is not notified again
+	c.onNotificationEvent(ev)
+
+	got := rec.Events(EV_NOTIFICATION)
+	if len(got) != 1 {
+		t.Fatalf("%d notification events, want 1", len(got))
+	}
+	n, ok := got[0].Payload.(*domain.Notification)
+	if !ok {
+		t.Fatalf("Payload is %T, want *domain.Notification", got[0].Payload)
+	}
+	if n.Id != ev.ID || n.From != ev.PubKey || n.Type != domain.NOTIFY_MENTION || n.Read {
+		t.Errorf("Notification %+v, want an unread mention from the sender", n)
+	}
+
+	// Our own events are not notifications
+	rec.Reset()
+	c.onNotificationEvent(addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{{"p", c.Config.Pubkey}}, "Me", nostr.Now()))
+	if got := rec.Events(EV_NOTIFICATION); len(got) != 0 {
+		t.Errorf("%d notification events for our own note, want none", len(got))
+	}
+}
+
+func TestEventDeletedEvent(t *testing.T) {
+	c, rec := newTestClient(t)
+	note := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Oops", nostr.Now())
+	if err := c.DeleteEvent(note.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rec.Events(EV_EVENT_DELETED)
+	if len(got) != 1 {
+		t.Fatalf("%d deleted events, want 1", len(got))
+	}
+	if !reflect.DeepEqual(got[0].Payload, []string{note.ID}) {
+		t.Errorf("Deleted %v, want %s", got[0].Payload, note.ID)
+	}
+
+	// Someone else cannot delete our notes
+	rec.Reset()
+	other := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Keep", nostr.Now())
+	c.applyDeletion(signedBy(t, nostr.GeneratePrivateKey(), nostr.KindDeletion, nostr.Tags{{"e", other.ID}}, ""))
+	if got := rec.Events(EV_EVENT_DELETED); len(got) != 0 {
+		t.Errorf("Deletion by another user emitted %v", got)
+	}
+}
+
+func TestEventsGoToEverySink(t *testing.T) {
+	c, rec := newTestClient(t)
+	extra := NewRecorder()
+	c.Events.AddSink(extra)
+	c.PingTimer()
+	c.Events.RemoveSink(extra)
+	c.PingTimer()
+
+	if got := rec.Events(EV_TIMER); len(got) != 2 {
+		t.Errorf("%d timer events on the first sink, want 2", len(got))
+	}
+	if got := extra.Events(EV_TIMER); len(got) != 1 {
+		t.Errorf("%d timer events on the removed sink, want 1", len(got))
+	}
+}
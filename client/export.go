@@ -0,0 +1,154 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Longest line read when importing, events with big contact lists are large
+const MAX_IMPORT_LINE = 4 * 1024 * 1024
+
+type ImportResult struct {
+	Read     int `json:"read"`
+	Imported int `json:"imported"`
+	// Valid events the cache already had, or that a newer version or a
+	// deletion replaces
+	Skipped   int            `json:"skipped"`
+	Invalid   int            `json:"invalid"`
+	Published map[string]int `json:"published"`
+}
+
+// ExportEvents writes the cached events matching the filter as JSON lines,
+// oldest first. With fetch the cache is first brought up to date with the
+// relays.
+func (c *Client) ExportEvents(w io.Writer, filter nostr.Filter, fetch bool) (int, error) {
+	if fetch {
+		// Reconciling ignores the limit, so a limited export just queries
+		var fetched []*nostr.Event
+		if filter.Limit > 0 {
+			fetched = c.Pool.QueryAll(&filter)
+		} else {
+			fetched = c.Pool.Pull(context.Background(), &filter, c.DB.QueryEvents(&filter))
+		}
+		for _, ev := range fetched {
+			c.DB.AddEvent(ev.ID, ev)
+		}
+	}
+	events := []*nostr.Event{}
+	for _, ev := range c.DB.QueryEvents(&filter) {
+		if !c.DB.IsDeleted(ev) {
+			events = append(events, ev)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt < events[j].CreatedAt
+	})
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, ev := range events {
+		if _, err := fmt.Fprintln(bw, ev.String()); err != nil {
+			return 0, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	log.Info().Msgf("Exported %d events", len(events))
+	return len(events), nil
+}
+
+// ExportEventsToFile saves events matching the filter to a JSONL file. Our
+// own events are fetched from the relays first; with all set the whole cache
+// is exported as it is.
+func (c *Client) ExportEventsToFile(path string, filter nostr.Filter, all bool) (int, error) {
+	if !all {
+		if c.Config.Pubkey == "" {
+			return 0, errors.New("Not logged in")
+		}
+		filter.Authors = []string{c.Config.Pubkey}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.ExportEvents(f, filter, !all)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// ImportEvents reads JSON lines of signed events. Each one is verified and
+// cached, deletions among them are applied, and when relays are given every
+// event is republished to them, which moves an account to new relays.
+func (c *Client) ImportEvents(r io.Reader, relays []string) (*ImportResult, error) {
+	result := &ImportResult{Published: map[string]int{}}
+	targets := []string{}
+	for _, url := range relays {
+		relay := c.Pool.GetRelayByUrl(url)
+		if relay == nil || !relay.Enabled || !relay.Write || !relay.Connected() {
+			return nil, errors.New("Not a connected write relay: " + url)
+		}
+		targets = append(targets, url)
+		result.Published[url] = 0
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MAX_IMPORT_LINE)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		result.Read++
+
+		var ev nostr.Event
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			log.Debug().Msgf("Skipping import line %d: %s", result.Read, err.Error())
+			result.Invalid++
+			continue
+		}
+		if err := c.Pool.Verify(&ev); err != nil {
+			log.Debug().Msgf("Skipping imported event %s: %s", ev.ID, err.Error())
+			result.Invalid++
+			continue
+		}
+		if !c.DB.HasEvent(ev.ID) && c.DB.AddEvent(ev.ID, &ev) {
+			result.Imported++
+		} else {
+			result.Skipped++
+		}
+		c.applyDeletion(&ev)
+
+		for _, url := range c.publishTo(&ev, targets) {
+			result.Published[url]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	log.Info().Msgf("Imported %d of %d events, %d skipped, %d invalid", result.Imported, result.Read, result.Skipped, result.Invalid)
+	return result, nil
+}
+
+// ImportEventsFromFile imports a JSONL file, see ImportEvents
+func (c *Client) ImportEventsFromFile(path string, relays []string) (*ImportResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return c.ImportEvents(f, relays)
+}
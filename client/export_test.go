@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"github.com/nbd-wtf/go-nostr"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	c, _ := newTestClient(t)
+	now := nostr.Now()
+	first := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "First", now-30)
+	second := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Second", now-20)
+	gone := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Gone", now-10)
+	if err := c.DeleteEvent(gone.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	mine := nostr.Filter{Authors: []string{c.Config.Pubkey}}
+	n, err := c.ExportEvents(&buf, mine, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The deleted note stays behind, its deletion request goes
+	if n != 3 {
+		t.Fatalf("Exported %d events, want 3", n)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], first.ID) || !strings.Contains(lines[1], second.ID) {
+		t.Error("Export is not oldest first")
+	}
+
+	other, _ := newTestClient(t)
+	other.DB.AddEvent(gone.ID, gone)
+	result, err := other.ImportEvents(strings.NewReader(buf.String()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Read != 3 || result.Imported != 3 || result.Skipped != 0 || result.Invalid != 0 {
+		t.Errorf("Import %+v, want 3 read and imported", result)
+	}
+	for _, ev := range []*nostr.Event{first, second} {
+		if other.DB.GetEvent(ev.ID) == nil {
+			t.Errorf("Event %s not imported", ev.ID)
+		}
+	}
+	if !other.DB.IsDeleted(gone) {
+		t.Error("Imported deletion not applied")
+	}
+
+	// Importing again brings nothing new
+	result, err = other.ImportEvents(strings.NewReader(buf.String()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Read != 3 || result.Imported != 0 || result.Skipped != 3 {
+		t.Errorf("Second import %+v, want all 3 skipped", result)
+	}
+}
+
+func TestImportSkipsInvalid(t *testing.T) {
+	c, _ := newTestClient(t)
+	good := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Good", nostr.Now())
+	forged := *good
+	forged.Content = "Forged"
+
+	other, _ := newTestClient(t)
+	input := good.String() + "\n\nnot json\n" + forged.String() + "\n"
+	result, err := other.ImportEvents(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Read != 3 || result.Imported != 1 || result.Invalid != 2 {
+		t.Errorf("Import %+v, want 3 read, 1 imported and 2 invalid", result)
+	}
+	if _, err := other.ImportEvents(strings.NewReader(""), []string{"wss://nowhere.example"}); err == nil {
+		t.Error("Imported to a relay not in the pool")
+	}
+}
+
+func TestExportLimitAndFile(t *testing.T) {
+	c, _ := newTestClient(t)
+	now := nostr.Now()
+	for i := 0; i < 5; i++ {
+		addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Note", now-nostr.Timestamp(50-i))
+	}
+	newest := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Newest", now)
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	n, err := c.ExportEventsToFile(path, nostr.Filter{Limit: 2}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("Exported %d events, want the limit of 2", n)
+	}
+
+	other, _ := newTestClient(t)
+	result, err := other.ImportEventsFromFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || other.DB.GetEvent(newest.ID) == nil {
+		t.Errorf("Import %+v, want the 2 newest events", result)
+	}
+}
@@ -0,0 +1,295 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"sort"
+	"strings"
+)
+
+// parseFollowSet reads a follow set event. If the private members cannot be
+// decrypted the set comes back with the public ones and the error.
+func (c *Client) parseFollowSet(ev *nostr.Event) (*domain.FollowSet, error) {
+	set := domain.FollowSet{
+		Pubkeys: []string{},
+		Private: []string{},
+	}
+	if d := ev.Tags.GetFirst([]string{"d", ""}); d != nil {
+		set.Name = d.Value()
+	}
+	if title := ev.Tags.GetFirst([]string{"title", ""}); title != nil {
+		set.Title = title.Value()
+	}
+	for _, tag := range ev.Tags.GetAll([]string{"p", ""}) {
+		if !domain.Contains(set.Pubkeys, tag.Value()) {
+			set.Pubkeys = append(set.Pubkeys, tag.Value())
+		}
+	}
+
+	private, err := c.decryptPrivateTags(ev.Content)
+	if err != nil {
+		return &set, fmt.Errorf("Could not decrypt private follow set members in %s: %w", ev.ID, err)
+	}
+	for _, tag := range private.GetAll([]string{"p", ""}) {
+		if !domain.Contains(set.Private, tag.Value()) {
+			set.Private = append(set.Private, tag.Value())
+		}
+	}
+	return &set, nil
+}
+
+// getFollowSet returns nil and an error for a set we do not have. A set
+// whose private members could not be read comes with an error as well, and
+// must not be saved back.
+func (c *Client) getFollowSet(name string) (*domain.FollowSet, error) {
+	if name == "" || name == domain.FEED_CONTACTS {
+		return nil, errors.New("Invalid follow set name: " + name)
+	}
+	ev := c.getLatestList(domain.KIND_FOLLOW_SET, name)
+	if ev == nil {
+		return nil, errors.New("No such follow set: " + name)
+	}
+	return c.parseFollowSet(ev)
+}
+
+// editFollowSet is getFollowSet for changing a set, which needs all of it
+func (c *Client) editFollowSet(name string) (*domain.FollowSet, error) {
+	set, err := c.getFollowSet(name)
+	if set != nil && err != nil {
+		return nil, fmt.Errorf("%w, not changing the set", err)
+	}
+	return set, err
+}
+
+func (c *Client) GetFollowSets() []*domain.FollowSet {
+	sets := []*domain.FollowSet{}
+	for _, ev := range c.getLatestSets(domain.KIND_FOLLOW_SET) {
+		set, err := c.parseFollowSet(ev)
+		if err != nil {
+			log.Error().Msg(err.Error())
+		}
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Name < sets[j].Name
+	})
+	return sets
+}
+
+func (c *Client) CreateFollowSet(name string, title string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || name == domain.FEED_CONTACTS {
+		return errors.New("Invalid follow set name: " + name)
+	}
+	if set, _ := c.getFollowSet(name); set != nil {
+		return errors.New("Follow set already exists: " + name)
+	}
+
+	return c.SaveFollowSet(domain.FollowSet{
+		Name:    name,
+		Title:   title,
+		Pubkeys: []string{},
+		Private: []string{},
+	})
+}
+
+// SaveFollowSet publishes the set over the latest version, whose other tags,
+// such as an image or ones from other clients, are kept
+func (c *Client) SaveFollowSet(set domain.FollowSet) error {
+	if set.Name == "" || set.Name == domain.FEED_CONTACTS {
+		return errors.New("Invalid follow set name: " + set.Name)
+	}
+
+	public := nostr.Tags{nostr.Tag{"d", set.Name}}
+	private := nostr.Tags{}
+	if latest := c.getLatestList(domain.KIND_FOLLOW_SET, set.Name); latest != nil {
+		var err error
+		private, err = c.decryptPrivateTags(latest.Content)
+		if err != nil {
+			return fmt.Errorf("Could not decrypt private follow set members in %s: %w, not changing the set", latest.ID, err)
+		}
+		public = latest.Tags
+	}
+
+	members := nostr.Tags{}
+	for _, pk := range set.Pubkeys {
+		members = append(members, nostr.Tag{"p", pk})
+	}
+	public = domain.ReplaceListItems(domain.SetTagValue(public, "title", set.Title), isPubkeyTag, members)
+	members = nostr.Tags{}
+	for _, pk := range set.Private {
+		members = append(members, nostr.Tag{"p", pk})
+	}
+	private = domain.ReplaceListItems(private, isPubkeyTag, members)
+
+	content, err := c.encryptPrivateTags(private)
+	if err != nil {
+		return err
+	}
+	c.signAndPublish(domain.KIND_FOLLOW_SET, public, content)
+	return nil
+}
+
+func isPubkeyTag(tag nostr.Tag) bool {
+	return tag.Key() == "p"
+}
+
+func (c *Client) AddToFollowSet(name string, pk string, private bool) error {
+	set, err := c.editFollowSet(name)
+	if err != nil {
+		return err
+	}
+	set.Remove(pk)
+	if private {
+		set.Private = append(set.Private, pk)
+	} else {
+		set.Pubkeys = append(set.Pubkeys, pk)
+	}
+	return c.SaveFollowSet(*set)
+}
+
+func (c *Client) RemoveFromFollowSet(name string, pk string) error {
+	set, err := c.editFollowSet(name)
+	if err != nil {
+		return err
+	}
+	set.Remove(pk)
+	return c.SaveFollowSet(*set)
+}
+
+// MoveToFollowSet moves a person from one set to another, keeping them
+// private if they were private in the original set. Moving within a set
+// does nothing.
+func (c *Client) MoveToFollowSet(from string, to string, pk string) error {
+	if from == to {
+		return nil
+	}
+	src, err := c.editFollowSet(from)
+	if err != nil {
+		return err
+	}
+	dst, err := c.editFollowSet(to)
+	if err != nil {
+		return err
+	}
+
+	private := domain.Contains(src.Private, pk)
+	src.Remove(pk)
+	dst.Remove(pk)
+	if private {
+		dst.Private = append(dst.Private, pk)
+	} else {
+		dst.Pubkeys = append(dst.Pubkeys, pk)
+	}
+
+	err = c.SaveFollowSet(*dst)
+	if err != nil {
+		return err
+	}
+	return c.SaveFollowSet(*src)
+}
+
+func (c *Client) GetFeeds() []*domain.Feed {
+	feeds := []*domain.Feed{{
+		Id:    domain.FEED_CONTACTS,
+		Title: "Following",
+		Size:  len(c.followedPks),
+	}}
+	for _, set := range c.GetFollowSets() {
+		title := set.Title
+		if title == "" {
+			title = set.Name
+		}
+		feeds = append(feeds, &domain.Feed{
+			Id:    set.Name,
+			Title: title,
+			Size:  len(set.Members()),
+		})
+	}
+	return feeds
+}
+
+// FeedPubkeys resolves a feed ID to the pubkeys whose notes make up the feed
+func (c *Client) FeedPubkeys(feedId string) ([]string, error) {
+	if feedId == "" || feedId == domain.FEED_CONTACTS {
+		return c.followedPks, nil
+	}
+	set, err := c.getFollowSet(feedId)
+	if set == nil {
+		return nil, err
+	}
+	if err != nil {
+		// The public members still make a feed
+		log.Error().Msg(err.Error())
+	}
+	return set.Members(), nil
+}
+
+// QueryFeed fetches the notes and boosts of a feed since ts, oldest first.
+// Muted and deleted events are left out. Only the events missing from the
+// cache are transferred from the relays.
+func (c *Client) QueryFeed(feedId string, since nostr.Timestamp) ([]*nostr.Event, error) {
+	pks, err := c.FeedPubkeys(feedId)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*nostr.Event{}
+	for _, chk := range domain.ChunkSlice(pks, QUERY_SIZE) {
+		filter := nostr.Filter{
+			Authors: chk,
+			Kinds:   []int{nostr.KindTextNote, nostr.KindBoost},
+			Since:   &since,
+		}
+		for _, ev := range c.Pool.Pull(context.Background(), &filter, c.DB.QueryEvents(&filter)) {
+			c.DB.AddEvent(ev.ID, ev)
+		}
+		for _, ev := range c.DB.QueryEvents(&filter) {
+			if !c.Mutes.IsMuted(ev) && !c.DB.IsDeleted(ev) {
+				events = append(events, ev)
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt < events[j].CreatedAt
+	})
+	return events, nil
+}
+
+// SelectFeed switches the timeline to another feed and resubscribes
+func (c *Client) SelectFeed(feedId string) error {
+	if _, err := c.FeedPubkeys(feedId); err != nil {
+		return err
+	}
+	log.Debug().Msgf("Selecting feed %s", feedId)
+	c.feedId = feedId
+	c.RefreshFeedReset()
+	return nil
+}
+
+func (c *Client) GetSelectedFeed() string {
+	if c.feedId == "" {
+		return domain.FEED_CONTACTS
+	}
+	return c.feedId
+}
+
+// SubscribeToFeed subscribes to the notes of everyone in the given feed, in
+// place of the feed subscribed before
+func (c *Client) SubscribeToFeed(feedId string, repost bool) error {
+	pks, err := c.FeedPubkeys(feedId)
+	if err != nil {
+		return err
+	}
+
+	c.unsubscribeFeed()
+	chks := domain.ChunkSlice(pks, QUERY_SIZE)
+	for _, chk := range chks {
+		c.SubscribeToFeedForPubkeys(chk, repost)
+	}
+	return nil
+}
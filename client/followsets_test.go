@@ -0,0 +1,97 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+	"reflect"
+	"testing"
+)
+
+func TestFollowSetKeepsPrivateMembers(t *testing.T) {
+	c, _ := newTestClient(t)
+	content, err := c.encryptPrivateTags(nostr.Tags{{"p", "bb"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addOwnEvent(t, c, domain.KIND_FOLLOW_SET, nostr.Tags{{"d", "friends"}, {"p", "aa"}}, content, nostr.Now()-10)
+
+	if err := c.AddToFollowSet("friends", "cc", false); err != nil {
+		t.Fatal(err)
+	}
+	set, err := c.getFollowSet("friends")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Pubkeys) != 2 || len(set.Private) != 1 || set.Private[0] != "bb" {
+		t.Errorf("set after adding: %+v", set)
+	}
+}
+
+func TestFollowSetRefusesUndecryptableSet(t *testing.T) {
+	c, _ := newTestClient(t)
+	addOwnEvent(t, c, domain.KIND_FOLLOW_SET, nostr.Tags{{"d", "friends"}, {"p", "aa"}}, "not a payload", nostr.Now()-10)
+	addOwnEvent(t, c, domain.KIND_FOLLOW_SET, nostr.Tags{{"d", "others"}}, "", nostr.Now()-10)
+
+	if err := c.AddToFollowSet("friends", "cc", false); err == nil {
+		t.Error("AddToFollowSet published over a set it could not decrypt")
+	}
+	if err := c.RemoveFromFollowSet("friends", "aa"); err == nil {
+		t.Error("RemoveFromFollowSet published over a set it could not decrypt")
+	}
+	if err := c.MoveToFollowSet("friends", "others", "aa"); err == nil {
+		t.Error("MoveToFollowSet published over a set it could not decrypt")
+	}
+	if set, _ := c.getFollowSet("others"); len(set.Pubkeys) != 0 {
+		t.Errorf("member moved out of an unreadable set: %+v", set)
+	}
+	if pks, err := c.FeedPubkeys("friends"); err != nil || len(pks) != 1 {
+		t.Errorf("public members not in the feed: %v, %v", pks, err)
+	}
+	if err := c.CreateFollowSet("friends", ""); err == nil {
+		t.Error("CreateFollowSet replaced an unreadable set")
+	}
+}
+
+func TestMoveToSameFollowSet(t *testing.T) {
+	c, _ := newTestClient(t)
+	old := addOwnEvent(t, c, domain.KIND_FOLLOW_SET, nostr.Tags{{"d", "friends"}, {"p", "aa"}}, "", nostr.Now()-10)
+
+	if err := c.MoveToFollowSet("friends", "friends", "aa"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, domain.KIND_FOLLOW_SET, "friends"); ev.ID != old.ID {
+		t.Errorf("set republished as %s", ev.ID)
+	}
+}
+
+func TestFollowSetEditKeepsOtherTags(t *testing.T) {
+	c, _ := newTestClient(t)
+	content, err := c.encryptPrivateTags(nostr.Tags{{"p", "bb"}, {"note", "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addOwnEvent(t, c, domain.KIND_FOLLOW_SET, nostr.Tags{
+		{"d", "friends"}, {"title", "Friends"}, {"image", "https://example.com/a.png"},
+		{"description", "People I know"}, {"p", "aa", "wss://relay.example", "alice"}, {"x-unknown", "kept"},
+	}, content, nostr.Now()-10)
+
+	if err := c.AddToFollowSet("friends", "cc", false); err != nil {
+		t.Fatal(err)
+	}
+	ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, domain.KIND_FOLLOW_SET, "friends")
+	want := nostr.Tags{
+		{"d", "friends"}, {"title", "Friends"}, {"image", "https://example.com/a.png"},
+		{"description", "People I know"}, {"x-unknown", "kept"},
+		{"p", "aa", "wss://relay.example", "alice"}, {"p", "cc"},
+	}
+	if !reflect.DeepEqual(ev.Tags, want) {
+		t.Errorf("Tags after adding %v, want %v", ev.Tags, want)
+	}
+	private, err := c.decryptPrivateTags(ev.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(private, nostr.Tags{{"note", "secret"}, {"p", "bb"}}) {
+		t.Errorf("Private tags after adding %v", private)
+	}
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"greet/crypto"
+	"greet/domain"
+	"strings"
+)
+
+// getLatestList fetches the newest version of one of our own lists, or any
+// other replaceable event of ours. For parameterized kinds d is the
+// identifier, otherwise blank.
+func (c *Client) getLatestList(kind int, d string) *nostr.Event {
+	filter := nostr.Filter{
+		Authors: []string{c.Config.Pubkey},
+		Kinds:   []int{kind},
+	}
+	if d != "" {
+		filter.Tags = nostr.TagMap{"d": []string{d}}
+	}
+
+	// The cache keeps the newest version, so lists still work when relays
+	// are down
+	for _, ev := range c.Pool.QueryAll(&filter) {
+		c.DB.AddEvent(ev.ID, ev)
+	}
+	return c.DB.GetReplaceableEvent(c.Config.Pubkey, kind, d)
+}
+
+// getLatestSets returns the newest version of each of our parameterized lists
+// of the given kind, keyed by "d" tag
+func (c *Client) getLatestSets(kind int) map[string]*nostr.Event {
+	filter := nostr.Filter{
+		Authors: []string{c.Config.Pubkey},
+		Kinds:   []int{kind},
+	}
+
+	for _, ev := range c.Pool.QueryAll(&filter) {
+		c.DB.AddEvent(ev.ID, ev)
+	}
+	sets := make(map[string]*nostr.Event)
+	for _, ev := range c.DB.QueryEvents(&filter) {
+		if d := domain.TagValue(ev.Tags, "d"); d != "" {
+			sets[d] = ev
+		}
+	}
+	return sets
+}
+
+// decryptPrivateTags returns the private items of a list, which are stored as
+// an encrypted JSON tag array in the content. Older clients used NIP-04.
+func (c *Client) decryptPrivateTags(content string) (nostr.Tags, error) {
+	tags := nostr.Tags{}
+	if content == "" {
+		return tags, nil
+	}
+	if c.Config.PrivKeyHex == "" {
+		return tags, errors.New("Private key not available")
+	}
+
+	var plain string
+	if strings.Contains(content, "?iv=") {
+		key, err := nip04.ComputeSharedSecret(c.Config.Pubkey, c.Config.PrivKeyHex)
+		if err != nil {
+			return tags, err
+		}
+		plain, err = nip04.Decrypt(content, key)
+		if err != nil {
+			return tags, err
+		}
+	} else {
+		key, err := crypto.Nip44ConversationKey(c.Config.Pubkey, c.Config.PrivKeyHex)
+		if err != nil {
+			return tags, err
+		}
+		plain, err = crypto.Nip44Decrypt(content, key)
+		if err != nil {
+			return tags, err
+		}
+	}
+
+	err := json.Unmarshal([]byte(plain), &tags)
+	return tags, err
+}
+
+func (c *Client) encryptPrivateTags(tags nostr.Tags) (string, error) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+	j, err := json.Marshal(tags)
+	if err != nil {
+		return "", err
+	}
+	key, err := crypto.Nip44ConversationKey(c.Config.Pubkey, c.Config.PrivKeyHex)
+	if err != nil {
+		return "", err
+	}
+	return crypto.Nip44Encrypt(string(j), key)
+}
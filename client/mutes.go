@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"strings"
+)
+
+// emitNote sends a note to the frontend unless it is muted or deleted
+func (c *Client) emitNote(name string, ev *nostr.Event) {
+	if c.Mutes.IsMuted(ev) {
+		log.Trace().Msgf("Muted event %s", ev.ID)
+		return
+	}
+	if c.DB.IsDeleted(ev) {
+		log.Trace().Msgf("Deleted event %s", ev.ID)
+		return
+	}
+	c.Events.Note(name, ev)
+}
+
+func (c *Client) LoadMuteList() {
+	log.Debug().Msg("Loading mute list")
+	ev := c.getLatestList(domain.KIND_MUTE_LIST, "")
+	if ev == nil {
+		return
+	}
+
+	// Without the private part the public mutes still apply
+	private, err := c.decryptPrivateTags(ev.Content)
+	if err != nil {
+		log.Error().Msgf("Could not decrypt private mutes: %s", err.Error())
+	}
+	entries, _, _ := domain.ParseMuteTags(ev.Tags, private)
+	c.Mutes.Set(entries)
+	log.Debug().Msgf("Loaded %d mute entries", len(entries))
+}
+
+func (c *Client) GetMuteList() []*domain.MuteEntry {
+	return c.Mutes.Entries()
+}
+
+func (c *Client) Mute(muteType string, value string, private bool) error {
+	if !domain.IsMuteType(muteType) {
+		return errors.New("Unknown mute type: " + muteType)
+	}
+	if muteType == domain.MUTE_PUBKEY && strings.HasPrefix(value, "npub") {
+		val, err := c.Nip19Decode(value)
+		if err != nil {
+			return err
+		}
+		value = val.PubKey
+	}
+	if value == "" {
+		return errors.New("Nothing to mute")
+	}
+
+	return c.editMuteList(func(entries []*domain.MuteEntry) []*domain.MuteEntry {
+		kept := removeMute(entries, muteType, value)
+		return append(kept, &domain.MuteEntry{Type: muteType, Value: value, Private: private})
+	})
+}
+
+func (c *Client) Unmute(muteType string, value string) error {
+	return c.editMuteList(func(entries []*domain.MuteEntry) []*domain.MuteEntry {
+		return removeMute(entries, muteType, value)
+	})
+}
+
+func removeMute(entries []*domain.MuteEntry, muteType string, value string) []*domain.MuteEntry {
+	kept := []*domain.MuteEntry{}
+	for _, e := range entries {
+		if e.Type != muteType || e.Value != value {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// editMuteList changes the latest published list so other clients' changes
+// survive. If its private part cannot be decrypted nothing is published, as
+// that would wipe the private mutes.
+func (c *Client) editMuteList(edit func(entries []*domain.MuteEntry) []*domain.MuteEntry) error {
+	if c.Config.PrivKeyHex == "" {
+		return errors.New("Private key not available")
+	}
+	public, private := nostr.Tags{}, nostr.Tags{}
+	if ev := c.getLatestList(domain.KIND_MUTE_LIST, ""); ev != nil {
+		var err error
+		private, err = c.decryptPrivateTags(ev.Content)
+		if err != nil {
+			return fmt.Errorf("Could not decrypt private mutes, not changing the list: %w", err)
+		}
+		public = ev.Tags
+	}
+	entries, otherPublic, otherPrivate := domain.ParseMuteTags(public, private)
+	entries = edit(entries)
+
+	tags, privateTags := domain.MuteTags(entries, otherPublic, otherPrivate)
+	content, err := c.encryptPrivateTags(privateTags)
+	if err != nil {
+		return err
+	}
+	c.signAndPublish(domain.KIND_MUTE_LIST, tags, content)
+	c.Mutes.Set(entries)
+	return nil
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+	"testing"
+)
+
+func TestMuteKeepsPrivateAndUnknownTags(t *testing.T) {
+	c, _ := newTestClient(t)
+	content, err := c.encryptPrivateTags(nostr.Tags{{"word", "noise"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addOwnEvent(t, c, domain.KIND_MUTE_LIST, nostr.Tags{{"p", "aa"}, {"client", "other"}}, content, nostr.Now()-10)
+
+	if err := c.Mute(domain.MUTE_HASHTAG, "spam", false); err != nil {
+		t.Fatal(err)
+	}
+	ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, domain.KIND_MUTE_LIST, "")
+	if domain.TagValue(ev.Tags, "client") != "other" || domain.TagValue(ev.Tags, "t") != "spam" {
+		t.Errorf("published tags %v", ev.Tags)
+	}
+	private, err := c.decryptPrivateTags(ev.Content)
+	if err != nil || domain.TagValue(private, "word") != "noise" {
+		t.Errorf("private tags %v, %v", private, err)
+	}
+}
+
+func TestMuteRefusesUndecryptableList(t *testing.T) {
+	c, _ := newTestClient(t)
+	old := addOwnEvent(t, c, domain.KIND_MUTE_LIST, nostr.Tags{{"p", "aa"}}, "not a payload", nostr.Now()-10)
+
+	if err := c.Mute(domain.MUTE_WORD, "noise", true); err == nil {
+		t.Fatal("Mute published over a list it could not decrypt")
+	}
+	if err := c.Unmute(domain.MUTE_PUBKEY, "aa"); err == nil {
+		t.Fatal("Unmute published over a list it could not decrypt")
+	}
+	if ev := c.DB.GetReplaceableEvent(c.Config.Pubkey, domain.KIND_MUTE_LIST, ""); ev.ID != old.ID {
+		t.Errorf("list replaced by %s", ev.ID)
+	}
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip05"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const NIP05_TIMEOUT = time.Second * 10
+
+var ErrNip05NotFound = errors.New("NIP-05 identifier not found")
+
+// queryNip05 resolves an identifier through the domain's
+// /.well-known/nostr.json, returning the pubkey and any relay hints
+func queryNip05(identifier string) (string, []string, error) {
+	name, host, err := domain.SplitNip05(identifier)
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.Contains(host, ".") {
+		return "", nil, errors.New("Not a valid NIP-05 domain: " + host)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), NIP05_TIMEOUT)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("https://%s/.well-known/nostr.json?name=%s", host, url.QueryEscape(name)), nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// NIP-05 forbids following redirects
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("NIP-05 lookup for %s returned %s", identifier, res.Status)
+	}
+
+	var result nip05.WellKnownResponse
+	err = json.NewDecoder(res.Body).Decode(&result)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pk, ok := result.Names[name]
+	if !ok || !nostr.IsValidPublicKeyHex(pk) {
+		return "", nil, fmt.Errorf("%w: %s", ErrNip05NotFound, identifier)
+	}
+	return pk, result.Relays[pk], nil
+}
+
+// ResolvePubkey turns a hex key, npub, nprofile or NIP-05 identifier into a
+// hex public key
+func (c *Client) ResolvePubkey(user string) (string, error) {
+	if domain.IsNip05Identifier(user) {
+		pk, _, err := queryNip05(user)
+		return pk, err
+	}
+	return domain.DecodePubkey(user)
+}
+
+// VerifyNip05 checks that the NIP-05 identifier in a profile maps back to the
+// profile's pubkey. Results are cached on the profile for NIP05_TTL.
+func (c *Client) VerifyNip05(pk string) (bool, error) {
+	profile := c.DB.GetProfile(pk)
+	if profile == nil {
+		var err error
+		profile, err = c.GetContactProfile(pk)
+		if err != nil {
+			return false, err
+		}
+	}
+	if profile.Meta.NIP05 == "" {
+		return false, nil
+	}
+	if !profile.Nip05Expired() {
+		return profile.Nip05Verified, nil
+	}
+
+	resolved, relays, err := queryNip05(profile.Meta.NIP05)
+	if err != nil && !errors.Is(err, ErrNip05NotFound) {
+		// Network trouble is not a verdict, try again next time
+		log.Debug().Msgf("NIP-05 check for %s failed: %s", profile.Meta.NIP05, err.Error())
+		return false, err
+	}
+
+	// The cached profile is shared, change a copy and swap it in
+	checked := *profile
+	checked.Nip05Verified = resolved == pk
+	checked.Nip05CheckedAt = time.Now().Unix()
+	if checked.Nip05Verified && len(relays) > 0 {
+		checked.Relays = relays
+	}
+	profile = &checked
+	c.DB.AddProfile(pk, profile)
+	log.Debug().Msgf("NIP-05 %s for %s verified: %t", profile.Meta.NIP05, pk, profile.Nip05Verified)
+
+	if profile.Following {
+		c.Events.Metadata(profile)
+	}
+	return profile.Nip05Verified, nil
+}
+
+// checkNip05 verifies a profile's NIP-05 identifier in the background once
+// the cached result has expired, one check per pubkey at a time
+func (c *Client) checkNip05(profile *domain.Profile) {
+	if profile.Meta.NIP05 == "" || !profile.Nip05Expired() {
+		return
+	}
+	c.nip05Mu.Lock()
+	defer c.nip05Mu.Unlock()
+	if c.nip05Checks[profile.Pk] {
+		return
+	}
+	c.nip05Checks[profile.Pk] = true
+	go func() {
+		c.VerifyNip05(profile.Pk)
+		c.nip05Mu.Lock()
+		delete(c.nip05Checks, profile.Pk)
+		c.nip05Mu.Unlock()
+	}()
+}
+
+// LookupNip05 finds a profile by name@domain, using the relays the domain
+// lists for the pubkey as hints when fetching the metadata. The profile is
+// only marked verified if its own nip05 is the identifier. Without metadata
+// a blank profile for the pubkey comes back, and is not cached.
+func (c *Client) LookupNip05(identifier string) (*domain.Profile, error) {
+	log.Debug().Msgf("Looking up NIP-05 %s", identifier)
+	pk, relays, err := queryNip05(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := c.DB.GetProfile(pk)
+	if profile == nil {
+		events := c.Pool.QueryWithHints(&nostr.Filter{
+			Authors: []string{pk},
+			Kinds:   []int{nostr.KindSetMetadata},
+		}, relays)
+		for _, ev := range events {
+			c.addMetadataEvent(ev)
+		}
+		profile = c.DB.GetProfile(pk)
+	}
+	if profile == nil {
+		npub, _ := c.PkToNpub(pk)
+		return &domain.Profile{
+			Pk:        pk,
+			Following: domain.Contains(c.followedPks, pk),
+			Meta:      domain.ProfileMetadata{},
+			Npub:      npub,
+			Relays:    relays,
+		}, nil
+	}
+
+	name, host, _ := domain.SplitNip05(profile.Meta.NIP05)
+	wantName, wantHost, _ := domain.SplitNip05(identifier)
+	if name != wantName || host != wantHost {
+		return profile, nil
+	}
+	// The cached profile is shared, change a copy and swap it in
+	verified := *profile
+	verified.Nip05Verified = true
+	verified.Nip05CheckedAt = time.Now().Unix()
+	if len(relays) > 0 {
+		verified.Relays = relays
+	}
+	c.DB.AddProfile(pk, &verified)
+	return &verified, nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"greet/relays"
+)
+
+func (c *Client) Nip19Decode(uri string) (*domain.Nip19Entity, error) {
+	entity, err := domain.DecodeNip19(uri)
+	if err != nil {
+		log.Error().Msgf("Nip19Decode %s: %s", uri, err.Error())
+		return nil, err
+	}
+	log.Debug().Msgf("Nip19Decode: %s -> %s %+v", uri, entity.Type, entity)
+	return entity, nil
+}
+
+func (c *Client) EncodeNote(evId string) (string, error) {
+	return nip19.EncodeNote(evId)
+}
+
+// EncodeEvent makes an nevent for a note, with the author and relay hints if
+// we have the event cached
+func (c *Client) EncodeEvent(evId string) (string, error) {
+	ev := c.DB.GetEvent(evId)
+	if ev == nil {
+		return nip19.EncodeEvent(evId, []string{}, "")
+	}
+	return nip19.EncodeEvent(evId, c.relayHints(ev), ev.PubKey)
+}
+
+func (c *Client) EncodeProfile(pk string) (string, error) {
+	hints := []string{}
+	metadata := c.DB.QueryEvents(&nostr.Filter{
+		Authors: []string{pk},
+		Kinds:   []int{nostr.KindSetMetadata},
+	})
+	for _, ev := range metadata {
+		for _, r := range c.relayHints(ev) {
+			if !domain.Contains(hints, r) {
+				hints = append(hints, r)
+			}
+		}
+	}
+	if profile := c.DB.GetProfile(pk); profile != nil {
+		for _, r := range profile.Relays {
+			if !domain.Contains(hints, r) {
+				hints = append(hints, r)
+			}
+		}
+	}
+	if len(hints) > relays.MAX_RELAY_HINTS {
+		hints = hints[:relays.MAX_RELAY_HINTS]
+	}
+	return nip19.EncodeProfile(pk, hints)
+}
+
+// EncodeEntity makes an naddr for an addressable event
+func (c *Client) EncodeEntity(pk string, kind int, identifier string) (string, error) {
+	hints := []string{}
+	events := c.DB.QueryEvents(&nostr.Filter{
+		Authors: []string{pk},
+		Kinds:   []int{kind},
+		Tags:    nostr.TagMap{"d": []string{identifier}},
+	})
+	for _, ev := range events {
+		for _, r := range c.relayHints(ev) {
+			if !domain.Contains(hints, r) {
+				hints = append(hints, r)
+			}
+		}
+	}
+	if len(hints) > relays.MAX_RELAY_HINTS {
+		hints = hints[:relays.MAX_RELAY_HINTS]
+	}
+	return nip19.EncodeEntity(pk, kind, identifier, hints)
+}
+
+// EncodePrivateKey returns our own key as an nsec, for backing up
+func (c *Client) EncodePrivateKey() (string, error) {
+	if c.Config.PrivKeyHex == "" {
+		return "", errors.New("Private key not available")
+	}
+	return nip19.EncodePrivateKey(c.Config.PrivKeyHex)
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"greet/relays"
+	"sort"
+)
+
+// Notifications read one at a time that are remembered, the newest kept
+const MAX_READ_NOTIFICATIONS = 1000
+
+func (c *Client) SubscribeToNotifications() {
+	if c.Config.Pubkey == "" {
+		return
+	}
+	log.Debug().Msgf("Subscribing to notifications for %s", c.Config.Pubkey)
+
+	since := nostr.Now() - SECS_24H
+	filter := nostr.Filter{
+		Kinds: []int{
+			nostr.KindTextNote,
+			nostr.KindContactList,
+			nostr.KindBoost,
+			nostr.KindReaction,
+			nostr.KindZap,
+		},
+		Tags:  nostr.TagMap{"p": []string{c.Config.Pubkey}},
+		Since: &since,
+	}
+	handle := c.Pool.Subscribe(relays.SUB_NOTIFICATIONS, filter, func(ev *nostr.Event, live bool) {
+		c.onNotificationEvent(ev)
+	})
+
+	// Replaces the subscription for a key used before
+	c.subMu.Lock()
+	previous := c.notifySub
+	c.notifySub = handle
+	c.subMu.Unlock()
+	c.Pool.Unsubscribe(previous)
+}
+
+func (c *Client) onNotificationEvent(ev *nostr.Event) {
+	n := domain.ClassifyNotification(ev, c.Config.Pubkey)
+	if n == nil {
+		return
+	}
+	if ev.Kind == nostr.KindContactList {
+		// A follower's lists are cached to tell a new follow from an edit
+		// to a list we were already on
+		prev := c.DB.GetReplaceableEvent(ev.PubKey, nostr.KindContactList, "")
+		c.DB.AddEvent(ev.ID, ev)
+		if !domain.IsNewFollow(prev, ev, c.Config.Pubkey) {
+			return
+		}
+	}
+	if c.DB.HasNotification(n.Id) || c.Mutes.IsMuted(ev) || c.Mutes.IsMutedPubkey(n.From) {
+		return
+	}
+	if ev.Kind != nostr.KindContactList {
+		c.DB.AddEvent(ev.ID, ev)
+	}
+	c.configMu.Lock()
+	_, read := c.Config.ReadNotifications[n.Id]
+	n.Read = read || n.CreatedAt <= c.Config.NotificationsRead
+	c.configMu.Unlock()
+	c.DB.AddNotification(n)
+	c.Events.Notification(n)
+}
+
+func (c *Client) GetNotifications() []*domain.Notification {
+	ns := c.DB.GetNotifications()
+	sort.Slice(ns, func(i, j int) bool {
+		return ns[i].CreatedAt > ns[j].CreatedAt
+	})
+	return ns
+}
+
+func (c *Client) GetUnreadNotificationCount() int {
+	count := 0
+	for _, n := range c.DB.GetNotifications() {
+		if !n.Read {
+			count++
+		}
+	}
+	return count
+}
+
+// MarkNotificationsRead marks some notifications read, remembering them in
+// the config so they are still read next time
+func (c *Client) MarkNotificationsRead(ids []string) {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	if c.Config.ReadNotifications == nil {
+		c.Config.ReadNotifications = map[string]nostr.Timestamp{}
+	}
+	for _, id := range ids {
+		n := c.DB.GetNotification(id)
+		if n == nil {
+			continue
+		}
+		c.DB.MarkNotificationRead(id)
+		if n.CreatedAt > c.Config.NotificationsRead {
+			c.Config.ReadNotifications[id] = n.CreatedAt
+		}
+	}
+	c.saveReadNotifications()
+}
+
+func (c *Client) MarkAllNotificationsRead() {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	latest := c.Config.NotificationsRead
+	for _, n := range c.DB.GetNotifications() {
+		c.DB.MarkNotificationRead(n.Id)
+		if n.CreatedAt > latest {
+			latest = n.CreatedAt
+		}
+	}
+
+	// Remember where we got to so older notifications start read next time
+	c.Config.NotificationsRead = latest
+	c.saveReadNotifications()
+}
+
+// saveReadNotifications drops the ids the NotificationsRead time covers and
+// the oldest past MAX_READ_NOTIFICATIONS, then saves the config. Callers hold
+// configMu.
+func (c *Client) saveReadNotifications() {
+	read := c.Config.ReadNotifications
+	ids := []string{}
+	for id, at := range read {
+		if at <= c.Config.NotificationsRead {
+			delete(read, id)
+		} else {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > MAX_READ_NOTIFICATIONS {
+		sort.Slice(ids, func(i, j int) bool {
+			return read[ids[i]] > read[ids[j]]
+		})
+		for _, id := range ids[MAX_READ_NOTIFICATIONS:] {
+			delete(read, id)
+		}
+	}
+
+	err := c.Config.Save()
+	if err != nil {
+		log.Error().Msgf("Error saving config file: %s", err.Error())
+	}
+}
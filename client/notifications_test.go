@@ -0,0 +1,104 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/config"
+	"greet/domain"
+	"testing"
+)
+
+func TestMarkNotificationsReadPersists(t *testing.T) {
+	c, _ := newTestClient(t)
+	sk := nostr.GeneratePrivateKey()
+	read := signedBy(t, sk, nostr.KindTextNote, nostr.Tags{{"p", c.Config.Pubkey}}, "Read me")
+	unread := signedBy(t, sk, nostr.KindReaction, nostr.Tags{{"p", c.Config.Pubkey}}, "+")
+	c.onNotificationEvent(read)
+	c.onNotificationEvent(unread)
+
+	c.MarkNotificationsRead([]string{read.ID})
+	if n := c.GetUnreadNotificationCount(); n != 1 {
+		t.Errorf("%d unread, want 1", n)
+	}
+
+	// A restart reads the config again and gets the notifications anew
+	cfg := config.NewConfig()
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Pubkey = c.Config.Pubkey
+	restarted := New(cfg, NewRecorder())
+	restarted.onNotificationEvent(read)
+	restarted.onNotificationEvent(unread)
+	for _, n := range restarted.GetNotifications() {
+		if want := n.Id == read.ID; n.Read != want {
+			t.Errorf("Notification %s read %v after restart, want %v", n.Id, n.Read, want)
+		}
+	}
+
+	// Marking everything read moves the time forward and forgets the ids
+	restarted.MarkAllNotificationsRead()
+	if len(restarted.Config.ReadNotifications) != 0 {
+		t.Errorf("%d ids kept that the read time covers", len(restarted.Config.ReadNotifications))
+	}
+	if n := restarted.GetUnreadNotificationCount(); n != 0 {
+		t.Errorf("%d unread after marking all read", n)
+	}
+}
+
+func TestGetNotificationsReturnsCopies(t *testing.T) {
+	c, _ := newTestClient(t)
+	ev := signedBy(t, nostr.GeneratePrivateKey(), nostr.KindTextNote, nostr.Tags{{"p", c.Config.Pubkey}}, "Hi")
+	c.onNotificationEvent(ev)
+
+	listed := c.GetNotifications()
+	c.MarkNotificationsRead([]string{ev.ID})
+	if listed[0].Read {
+		t.Error("Listed notification changed when it was marked read")
+	}
+	if !c.GetNotifications()[0].Read {
+		t.Error("Notification not read after marking it")
+	}
+}
+
+func TestFollowerNotifiedOnlyWhenAdded(t *testing.T) {
+	c, rec := newTestClient(t)
+	me := c.Config.Pubkey
+	contactList := func(sk string, pks []string, at nostr.Timestamp) *nostr.Event {
+		ev := signedBy(t, sk, nostr.KindContactList, nostr.Tags{}, "")
+		for _, pk := range pks {
+			ev.Tags = append(ev.Tags, nostr.Tag{"p", pk})
+		}
+		ev.CreatedAt = at
+		if err := ev.Sign(sk); err != nil {
+			t.Fatal(err)
+		}
+		return ev
+	}
+	now := nostr.Now()
+
+	// Already following us, then following someone else as well
+	old := nostr.GeneratePrivateKey()
+	before := contactList(old, []string{"aa", me}, now-20)
+	c.DB.AddEvent(before.ID, before)
+	c.onNotificationEvent(contactList(old, []string{"aa", me, "bb"}, now-10))
+	// First seen with us among older follows
+	seen := nostr.GeneratePrivateKey()
+	c.onNotificationEvent(contactList(seen, []string{me, "aa"}, now-10))
+	if got := rec.Events(EV_NOTIFICATION); len(got) != 0 {
+		t.Fatalf("%d follower notifications for lists we were already on", len(got))
+	}
+
+	// Adding us to a list we were not on
+	rec.Reset()
+	added := nostr.GeneratePrivateKey()
+	c.onNotificationEvent(contactList(added, []string{"aa"}, now-20))
+	c.onNotificationEvent(contactList(added, []string{me, "aa"}, now-10))
+	got := rec.Events(EV_NOTIFICATION)
+	if len(got) != 1 {
+		t.Fatalf("%d follower notifications, want 1", len(got))
+	}
+	addedPk, _ := nostr.GetPublicKey(added)
+	if n := got[0].Payload.(*domain.Notification); n.Type != domain.NOTIFY_FOLLOWER || n.From != addedPk {
+		t.Errorf("Notification %+v, want a new follower", n)
+	}
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Events per page when none are asked for, and the most per page
+const (
+	FEED_PAGE_SIZE     = 50
+	MAX_FEED_PAGE_SIZE = 500
+)
+
+// FeedPage is a page of a feed, newest first. Next is the until for the page
+// after it, 0 once there is nothing older.
+type FeedPage struct {
+	Events []*nostr.Event  `json:"events"`
+	Next   nostr.Timestamp `json:"next"`
+}
+
+// feedCoverage is the time range of a feed already fetched from the relays,
+// for the members it had then. Pages inside it come from the cache.
+type feedCoverage struct {
+	members string
+	oldest  nostr.Timestamp
+	newest  nostr.Timestamp
+}
+
+type feedPages struct {
+	mu       sync.Mutex
+	coverage map[string]feedCoverage
+}
+
+// LoadOlder returns the count notes and boosts of a feed created before
+// until, for scrolling back; an until of 0 starts from now. Pages already
+// fetched come from the cache, others from the relays merged with it.
+func (c *Client) LoadOlder(feedId string, until nostr.Timestamp, count int) (*FeedPage, error) {
+	pks, err := c.FeedPubkeys(feedId)
+	if err != nil {
+		return nil, err
+	}
+	if len(pks) == 0 {
+		return &FeedPage{Events: []*nostr.Event{}}, nil
+	}
+	if count <= 0 {
+		count = FEED_PAGE_SIZE
+	} else if count > MAX_FEED_PAGE_SIZE {
+		count = MAX_FEED_PAGE_SIZE
+	}
+	if until <= 0 {
+		until = nostr.Now()
+	}
+	sorted := append([]string{}, pks...)
+	sort.Strings(sorted)
+	members := strings.Join(sorted, ",")
+
+	filter := nostr.Filter{
+		Authors: pks,
+		Kinds:   []int{nostr.KindTextNote, nostr.KindBoost},
+		Until:   &until,
+	}
+	if events, next, ok := c.cachedPage(feedId, members, &filter, count); ok {
+		log.Debug().Msgf("Feed %s page before %d from the cache", feedId, until)
+		return &FeedPage{Events: events, Next: next}, nil
+	}
+
+	// Chunks are paged separately, the page is complete above the highest
+	// floor of any of them
+	var floor nostr.Timestamp
+	for _, chk := range domain.ChunkSlice(pks, QUERY_SIZE) {
+		f := filter
+		f.Authors = chk
+		f.Limit = count
+		events, chunkFloor := c.Pool.QueryPage(context.Background(), &f)
+		for _, ev := range events {
+			c.DB.AddEvent(ev.ID, ev)
+		}
+		if chunkFloor > floor {
+			floor = chunkFloor
+		}
+	}
+	events, next := domain.PageEvents(c.feedEvents(&filter), count, floor)
+
+	oldest := next + 1
+	if next == 0 {
+		oldest = 0
+	}
+	c.coverFeed(feedId, members, oldest, until)
+	return &FeedPage{Events: events, Next: next}, nil
+}
+
+// feedEvents is the cached events matching a feed filter that are neither
+// muted nor deleted
+func (c *Client) feedEvents(f *nostr.Filter) []*nostr.Event {
+	events := []*nostr.Event{}
+	for _, ev := range c.DB.QueryEvents(f) {
+		if !c.Mutes.IsMuted(ev) && !c.DB.IsDeleted(ev) {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// cachedPage serves a page from the cache when the range fetched before
+// holds a full page below until
+func (c *Client) cachedPage(feedId, members string, f *nostr.Filter, count int) ([]*nostr.Event, nostr.Timestamp, bool) {
+	c.pages.mu.Lock()
+	cov, ok := c.pages.coverage[feedId]
+	c.pages.mu.Unlock()
+	if !ok || cov.members != members || *f.Until > cov.newest || *f.Until < cov.oldest {
+		return nil, 0, false
+	}
+
+	inside := []*nostr.Event{}
+	for _, ev := range c.feedEvents(f) {
+		if ev.CreatedAt >= cov.oldest {
+			inside = append(inside, ev)
+		}
+	}
+	if cov.oldest == 0 {
+		events, next := domain.PageEvents(inside, count, 0)
+		return events, next, true
+	}
+	if len(inside) < count {
+		return nil, 0, false
+	}
+	events, next := domain.PageEvents(inside, count, cov.oldest-1)
+	return events, next, true
+}
+
+// forget drops the covered ranges, as other relays may have more
+func (p *feedPages) forget() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.coverage = nil
+}
+
+// coverFeed records that [oldest, newest] of a feed has been fetched,
+// joining it to the range already covered when they meet
+func (c *Client) coverFeed(feedId, members string, oldest, newest nostr.Timestamp) {
+	c.pages.mu.Lock()
+	defer c.pages.mu.Unlock()
+	if c.pages.coverage == nil {
+		c.pages.coverage = map[string]feedCoverage{}
+	}
+	cov, ok := c.pages.coverage[feedId]
+	if ok && cov.members == members && oldest <= cov.newest && newest >= cov.oldest {
+		if cov.oldest < oldest {
+			oldest = cov.oldest
+		}
+		if cov.newest > newest {
+			newest = cov.newest
+		}
+	}
+	c.pages.coverage[feedId] = feedCoverage{members: members, oldest: oldest, newest: newest}
+}
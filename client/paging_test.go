@@ -0,0 +1,44 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"testing"
+)
+
+func TestLoadOlderWalksTheFeed(t *testing.T) {
+	c, _ := newTestClient(t)
+	c.followedPks = []string{c.Config.Pubkey}
+	now := nostr.Now()
+	for i := 0; i < 5; i++ {
+		addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Note", now-nostr.Timestamp(10*(i+1)))
+	}
+	gone := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{}, "Gone", now-5)
+	if err := c.DeleteEvent(gone.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	until := nostr.Timestamp(0)
+	for pages := 1; ; pages++ {
+		page, err := c.LoadOlder("", until, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ev := range page.Events {
+			if seen[ev.ID] {
+				t.Errorf("Note %s on two pages", ev.ID)
+			}
+			seen[ev.ID] = true
+		}
+		if page.Next == 0 {
+			if pages != 3 {
+				t.Errorf("%d pages, want 3", pages)
+			}
+			break
+		}
+		until = page.Next
+	}
+	if len(seen) != 5 || seen[gone.ID] {
+		t.Errorf("Paged through %d notes, want the 5 not deleted", len(seen))
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"greet/relays"
+)
+
+// GetEventProvenance lists the relays an event has been seen on
+func (c *Client) GetEventProvenance(evId string) *relays.EventProvenance {
+	if p := c.Pool.GetProvenance(evId); p != nil {
+		return p
+	}
+	return &relays.EventProvenance{Id: evId, Relays: []*relays.RelaySighting{}}
+}
+
+// relayHints lists relays an event was seen on, earliest first, for NIP-19
+// and tag hints. Relays we write to come first as they are the ones we know
+// are up.
+func (c *Client) relayHints(ev *nostr.Event) []string {
+	hints := []string{}
+	if ev == nil {
+		return hints
+	}
+	p := c.Pool.GetProvenance(ev.ID)
+	if p == nil {
+		if r := ev.GetExtraString("relay"); r != "" {
+			hints = append(hints, r)
+		}
+		return hints
+	}
+
+	others := []string{}
+	for _, r := range p.Relays {
+		if relay := c.Pool.GetRelayByUrl(r.Url); relay != nil && relay.Write {
+			hints = append(hints, r.Url)
+		} else {
+			others = append(others, r.Url)
+		}
+	}
+	hints = append(hints, others...)
+	if len(hints) > relays.MAX_RELAY_HINTS {
+		hints = hints[:relays.MAX_RELAY_HINTS]
+	}
+	return hints
+}
+
+func (c *Client) GetRelayRejectCounts() map[string]int64 {
+	return c.Pool.GetRejectCounts()
+}
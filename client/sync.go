@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"sync"
+)
+
+// History sync states
+const (
+	SYNC_FETCHING   = "fetching"
+	SYNC_PUBLISHING = "publishing"
+	SYNC_DONE       = "done"
+	SYNC_CANCELLED  = "cancelled"
+	SYNC_FAILED     = "failed"
+)
+
+type SyncProgress struct {
+	Target    string `json:"target"`
+	State     string `json:"state"`
+	Relay     string `json:"relay"`
+	Relays    int    `json:"relays"`
+	Found     int    `json:"found"`
+	OnTarget  int    `json:"onTarget"`
+	Missing   int    `json:"missing"`
+	Published int    `json:"published"`
+	Failed    int    `json:"failed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// syncJob is the history sync running in the background, if any
+type syncJob struct {
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	progress *SyncProgress
+}
+
+// SyncHistory starts copying all of our events that the target relay is
+// missing to it, in the background. Progress comes as evSyncProgress events.
+func (c *Client) SyncHistory(target string) error {
+	c.sync.mu.Lock()
+	defer c.sync.mu.Unlock()
+	if c.sync.cancel != nil {
+		return errors.New("A history sync is already running")
+	}
+	if err := c.checkSyncTarget(target); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.sync.cancel = cancel
+	go func() {
+		c.SyncHistoryTo(ctx, target)
+		c.sync.mu.Lock()
+		c.sync.cancel = nil
+		c.sync.mu.Unlock()
+		cancel()
+	}()
+	return nil
+}
+
+// CancelSync stops the running history sync. What was already published
+// stays on the target.
+func (c *Client) CancelSync() {
+	c.sync.mu.Lock()
+	defer c.sync.mu.Unlock()
+	if c.sync.cancel != nil {
+		c.sync.cancel()
+	}
+}
+
+// GetSyncProgress returns the progress of the running or last history sync
+func (c *Client) GetSyncProgress() *SyncProgress {
+	c.sync.mu.Lock()
+	defer c.sync.mu.Unlock()
+	if c.sync.progress == nil {
+		return nil
+	}
+	progress := *c.sync.progress
+	return &progress
+}
+
+func (c *Client) checkSyncTarget(target string) error {
+	if c.Config.Pubkey == "" {
+		return errors.New("Not logged in")
+	}
+	r := c.Pool.GetRelayByUrl(target)
+	if r == nil || !r.Enabled || !r.Write || !r.Connected() {
+		return errors.New("Not a connected write relay: " + target)
+	}
+	return nil
+}
+
+func (c *Client) reportSync(progress *SyncProgress) {
+	c.sync.mu.Lock()
+	copied := *progress
+	c.sync.progress = &copied
+	c.sync.mu.Unlock()
+	c.Events.SyncProgress(copied)
+}
+
+// SyncHistoryTo runs a history sync and waits for it. Our events are first
+// pulled from every other relay in the pool into the cache, then the cache is
+// reconciled with the target and what it lacks is published oldest first.
+// Superseded replaceable events and deleted ones are skipped.
+func (c *Client) SyncHistoryTo(ctx context.Context, target string) (*SyncProgress, error) {
+	progress := &SyncProgress{Target: target, State: SYNC_FETCHING}
+	if err := c.checkSyncTarget(target); err != nil {
+		progress.State, progress.Error = SYNC_FAILED, err.Error()
+		c.reportSync(progress)
+		return progress, err
+	}
+	c.reportSync(progress)
+
+	filter := nostr.Filter{Authors: []string{c.Config.Pubkey}}
+	for _, r := range c.Pool.Relays() {
+		if !r.Enabled || !r.Connected() || r.Url == target {
+			continue
+		}
+		progress.Relay = r.Url
+		rec, err := c.Pool.Reconcile(ctx, r.Url, &filter, c.DB.QueryEvents(&filter))
+		if ctx.Err() != nil {
+			return c.endSync(progress, SYNC_CANCELLED, ctx.Err())
+		}
+		if err != nil {
+			log.Warn().Msgf("History sync could not reconcile with %s: %s", r.Url, err.Error())
+			continue
+		}
+		c.cacheHistory(rec.Events)
+		progress.Relays++
+		progress.Found = len(c.DB.QueryEvents(&filter))
+		c.reportSync(progress)
+	}
+
+	// Caching drops old versions of replaceable events, and deleted ones are
+	// left out, leaving what the target should have
+	progress.Relay = target
+	local := []*nostr.Event{}
+	for _, ev := range c.DB.QueryEvents(&filter) {
+		if !c.DB.IsDeleted(ev) {
+			local = append(local, ev)
+		}
+	}
+	rec, err := c.Pool.Reconcile(ctx, target, &filter, local)
+	if ctx.Err() != nil {
+		return c.endSync(progress, SYNC_CANCELLED, ctx.Err())
+	}
+	if err != nil {
+		return c.endSync(progress, SYNC_FAILED, err)
+	}
+	c.cacheHistory(rec.Events)
+	progress.Relays++
+	progress.Found = len(local) + len(rec.Need)
+	progress.OnTarget = progress.Found - len(rec.Have)
+	progress.Relay = ""
+
+	missing := []*nostr.Event{}
+	for _, id := range rec.Have {
+		if ev := c.DB.GetEvent(id); ev != nil {
+			missing = append(missing, ev)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].CreatedAt < missing[j].CreatedAt
+	})
+	progress.State, progress.Missing = SYNC_PUBLISHING, len(missing)
+	c.reportSync(progress)
+
+	for i, ev := range missing {
+		if ctx.Err() != nil {
+			return c.endSync(progress, SYNC_CANCELLED, ctx.Err())
+		}
+		if len(c.publishTo(ev, []string{target})) > 0 {
+			progress.Published++
+		} else {
+			progress.Failed++
+		}
+		if i%10 == 9 {
+			c.reportSync(progress)
+		}
+	}
+	return c.endSync(progress, SYNC_DONE, nil)
+}
+
+// cacheHistory adds fetched events of ours to the cache and applies the
+// deletions among them
+func (c *Client) cacheHistory(events []*nostr.Event) {
+	for _, ev := range events {
+		c.DB.AddEvent(ev.ID, ev)
+	}
+	for _, ev := range events {
+		c.applyDeletion(ev)
+	}
+}
+
+func (c *Client) endSync(progress *SyncProgress, state string, err error) (*SyncProgress, error) {
+	progress.State = state
+	if err != nil {
+		progress.Error = err.Error()
+	}
+	log.Info().Msgf("History sync to %s %s: %d found, %d missing, %d published, %d failed",
+		progress.Target, state, progress.Found, progress.Missing, progress.Published, progress.Failed)
+	c.reportSync(progress)
+	return progress, err
+}
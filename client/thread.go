@@ -0,0 +1,138 @@
+package client
+
+import (
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/domain"
+)
+
+func (c *Client) getEventById(evId string) *nostr.Event {
+	ev := c.DB.GetEvent(evId)
+	if ev == nil {
+		c.GetTextNotesByEventIds([]string{evId})
+		ev = c.DB.GetEvent(evId)
+	}
+	return ev
+}
+
+func (c *Client) GetThread(evId string) (*domain.Thread, error) {
+	log.Debug().Msgf("Building thread for %s", evId)
+
+	focus := c.getEventById(evId)
+	if focus == nil {
+		return nil, errors.New("Event not found: " + evId)
+	}
+
+	rootId := domain.ThreadRootId(focus)
+	if rootId == "" {
+		rootId = focus.ID
+	}
+
+	// Walk up the reply chain to the root
+	ancestors := []*nostr.Event{}
+	ids := []string{focus.ID}
+	current := focus
+	for depth := 0; depth < domain.MAX_THREAD_DEPTH && current.ID != rootId; depth++ {
+		parentId := domain.ReplyToId(current)
+		if parentId == "" || domain.Contains(ids, parentId) {
+			break
+		}
+		parent := c.getEventById(parentId)
+		if parent == nil {
+			break
+		}
+		ancestors = append([]*nostr.Event{parent}, ancestors...)
+		ids = append(ids, parent.ID)
+		current = parent
+	}
+
+	root := c.getEventById(rootId)
+	if root == nil {
+		// Root is gone; hang the thread off the oldest ancestor we found
+		root = focus
+		if len(ancestors) > 0 {
+			root = ancestors[0]
+			ancestors = ancestors[1:]
+		}
+	} else if len(ancestors) > 0 && ancestors[0].ID == root.ID {
+		ancestors = ancestors[1:]
+	}
+	if !domain.Contains(ids, rootId) {
+		ids = append(ids, rootId)
+	}
+
+	// Replies from relays and cache
+	filter := nostr.Filter{
+		Kinds: []int{nostr.KindTextNote},
+		Tags:  nostr.TagMap{"e": ids},
+	}
+	for _, ev := range c.Pool.QueryAll(&filter) {
+		c.DB.AddEvent(ev.ID, ev)
+	}
+	replies := c.DB.QueryEvents(&filter)
+	replies = append(replies, ancestors...)
+	if root.ID != focus.ID {
+		replies = append(replies, focus)
+	}
+
+	return &domain.Thread{
+		RootId:    root.ID,
+		FocusId:   focus.ID,
+		Ancestors: ancestors,
+		Root:      domain.BuildThreadTree(root, replies),
+	}, nil
+}
+
+// markReplyTags turns the "e" tags of a reply into NIP-10 marked root/reply
+// tags and copies the "p" tags of the event being replied to
+func (c *Client) markReplyTags(tags nostr.Tags) nostr.Tags {
+	reply := domain.ReplyTag(tags)
+	if reply == nil {
+		return tags
+	}
+	parentId := reply.Value()
+	parent := c.getEventById(parentId)
+
+	// The root as tagged, unless the parent knows better
+	rootId := domain.ThreadRootId(&nostr.Event{Tags: tags})
+	if rootId == "" {
+		rootId = parentId
+	}
+	if parent != nil {
+		if id := domain.ThreadRootId(parent); id != "" {
+			rootId = id
+		}
+	}
+
+	marked := nostr.Tags{}
+	for _, tag := range tags {
+		if tag.Key() == "e" && !(len(tag) >= 4 && tag[3] == "mention") {
+			continue
+		}
+		marked = append(marked, tag)
+	}
+	marked = append(marked, nostr.Tag{"e", rootId, c.relayHint(rootId), "root"})
+	if parentId != rootId {
+		marked = append(marked, nostr.Tag{"e", parentId, c.relayHint(parentId), "reply"})
+	}
+
+	if parent != nil {
+		marked = marked.AppendUnique(nostr.Tag{"p", parent.PubKey})
+		for _, tag := range parent.Tags.GetAll([]string{"p"}) {
+			if tag.Value() != c.Config.Pubkey {
+				marked = marked.AppendUnique(tag)
+			}
+		}
+	}
+	return marked
+}
+
+// relayHint returns a relay the event was seen on, if known
+func (c *Client) relayHint(evId string) string {
+	hints := c.relayHints(c.DB.GetEvent(evId))
+	if len(hints) == 0 {
+		return ""
+	}
+	return hints[0]
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"reflect"
+	"testing"
+)
+
+func TestMarkReplyTags(t *testing.T) {
+	c, _ := newTestClient(t)
+	tests := []struct {
+		name string
+		tags nostr.Tags
+		want nostr.Tags
+	}{
+		{
+			name: "positional",
+			tags: nostr.Tags{{"e", "root"}, {"e", "parent"}},
+			want: nostr.Tags{{"e", "root", "", "root"}, {"e", "parent", "", "reply"}},
+		},
+		{
+			name: "positional direct reply",
+			tags: nostr.Tags{{"e", "root"}},
+			want: nostr.Tags{{"e", "root", "", "root"}},
+		},
+		{
+			name: "marked, mention kept",
+			tags: nostr.Tags{{"e", "other", "", "mention"}, {"e", "parent", "", "reply"}, {"e", "root", "", "root"}},
+			want: nostr.Tags{{"e", "other", "", "mention"}, {"e", "root", "", "root"}, {"e", "parent", "", "reply"}},
+		},
+		{
+			name: "not a reply",
+			tags: nostr.Tags{{"t", "nostr"}},
+			want: nostr.Tags{{"t", "nostr"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := c.markReplyTags(tt.tags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarkReplyTagsFromParent(t *testing.T) {
+	c, _ := newTestClient(t)
+	parent := addOwnEvent(t, c, nostr.KindTextNote, nostr.Tags{{"e", "root", "", "root"}, {"p", "someone"}}, "hi", nostr.Now())
+
+	got := c.markReplyTags(nostr.Tags{{"e", parent.ID}})
+	want := nostr.Tags{
+		{"e", "root", "", "root"},
+		{"e", parent.ID, "", "reply"},
+		{"p", c.Config.Pubkey},
+		{"p", "someone"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%v, want %v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+// Package config loads and saves the settings file.
+package config
+
+import (
+	"encoding/json"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"greet/relays"
+	"os"
+	"path/filepath"
+)
+
+// Where the local API and local relay listen unless configured otherwise
+const (
+	API_PORT         = 7447
+	LOCAL_RELAY_PORT = 4869
+)
+
+type Config struct {
+	Pubkey            string `json:"-"`
+	Privkey           string
+	PrivKeyHex        string `json:"-"`
+	Pin               string `json:"-"`
+	Relays            []*relays.Relay
+	Follows           []*string `json:"-"`
+	Dark              bool
+	NotificationsRead nostr.Timestamp
+	// Notifications read one at a time since NotificationsRead, by id
+	ReadNotifications map[string]nostr.Timestamp
+	// When the deletion requests of followed users were last fetched
+	DeletionsSynced   nostr.Timestamp
+	LastContactCount  int
+	ApiEnabled        bool
+	ApiPort           int
+	ApiToken          string
+	LocalRelayEnabled bool
+	LocalRelayPort    int
+	userConfigDir     string
+	configDir         string
+	configPath        string
+}
+
+func NewConfig() *Config {
+	userConfigDir, _ := os.UserConfigDir()
+	configDir := filepath.Join(userConfigDir, "greet")
+	configPath := filepath.Join(configDir, "config.json")
+
+	log.Debug().Msgf("User config dir %s", userConfigDir)
+	log.Debug().Msgf("Config dir %s", configDir)
+	log.Debug().Msgf("Config path %s", configPath)
+
+	return &Config{
+		Pubkey:            "",
+		Privkey:           "",
+		PrivKeyHex:        "",
+		Pin:               "",
+		Relays:            []*relays.Relay{},
+		Follows:           []*string{},
+		ReadNotifications: map[string]nostr.Timestamp{},
+		Dark:              true,
+		ApiPort:           API_PORT,
+		LocalRelayPort:    LOCAL_RELAY_PORT,
+		userConfigDir:     userConfigDir,
+		configDir:         configDir,
+		configPath:        configPath,
+	}
+}
+
+// Dir is the directory the config file is in, for other files kept
+// alongside it
+func (c *Config) Dir() string {
+	return c.configDir
+}
+
+func (c *Config) OpenConfigFile(flags int) (*os.File, error) {
+	_ = os.MkdirAll(c.configDir, 0755)
+	return openFile(c.configPath, flags)
+}
+
+func (c *Config) Load() error {
+
+	f, err := c.OpenConfigFile(os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fileinfo, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	filesize := fileinfo.Size()
+	buffer := make([]byte, filesize)
+	bytesRead, err := f.Read(buffer)
+	if err != nil {
+		return err
+	}
+
+	if bytesRead == 0 {
+		var j []byte
+		f.Close()
+		f, err = c.OpenConfigFile(os.O_CREATE | os.O_RDWR | os.O_TRUNC)
+		if err != nil {
+			return err
+		}
+		j, err = json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		f.Write(j)
+		return nil
+	}
+
+	err = json.Unmarshal(buffer, &c)
+	if err != nil {
+		log.Err(err)
+	}
+
+	return nil
+}
+
+func (c *Config) Save() error {
+	f, err := c.OpenConfigFile(os.O_CREATE | os.O_RDWR | os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	configOutput, err := prettyStruct(c)
+	if err != nil {
+		return err
+	}
+
+	f.Write([]byte(configOutput + "\n"))
+
+	return nil
+}
+
+func prettyStruct(data interface{}) (string, error) {
+	val, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(val), nil
+}
+
+func openFile(path string, flags int) (*os.File, error) {
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil && flags&os.O_CREATE == 0 {
+		// Does not exist? Create
+		return os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	}
+	return f, err
+}
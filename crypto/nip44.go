@@ -0,0 +1,154 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math/bits"
+)
+
+// NIP-44 v2 payload encryption. The ECDH shared secret is the same one NIP-04
+// uses, only the key derivation and cipher differ.
+
+const (
+	NIP44_VERSION  = 2
+	NIP44_MIN_SIZE = 1
+	NIP44_MAX_SIZE = 65535
+)
+
+func Nip44ConversationKey(pub string, sk string) ([]byte, error) {
+	shared, err := nip04.ComputeSharedSecret(pub, sk)
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Extract(sha256.New, shared, []byte("nip44-v2")), nil
+}
+
+func Nip44Encrypt(plaintext string, conversationKey []byte) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return Nip44EncryptWithNonce(plaintext, conversationKey, nonce)
+}
+
+func Nip44EncryptWithNonce(plaintext string, conversationKey []byte, nonce []byte) (string, error) {
+	if len(conversationKey) != 32 || len(nonce) != 32 {
+		return "", errors.New("nip44: invalid key or nonce")
+	}
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(conversationKey, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	padded, err := nip44Pad(plaintext)
+	if err != nil {
+		return "", err
+	}
+	cipher, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(padded))
+	cipher.XORKeyStream(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(nonce)
+	mac.Write(ciphertext)
+
+	payload := []byte{NIP44_VERSION}
+	payload = append(payload, nonce...)
+	payload = append(payload, ciphertext...)
+	payload = mac.Sum(payload)
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+func Nip44Decrypt(payload string, conversationKey []byte) (string, error) {
+	if len(payload) == 0 || payload[0] == '#' {
+		return "", errors.New("nip44: unknown encryption version")
+	}
+	if len(payload) < 132 || len(payload) > 87472 {
+		return "", errors.New("nip44: invalid payload size")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 99 || len(data) > 65603 {
+		return "", errors.New("nip44: invalid data size")
+	}
+	if data[0] != NIP44_VERSION {
+		return "", errors.New("nip44: unknown encryption version")
+	}
+
+	nonce := data[1:33]
+	ciphertext := data[33 : len(data)-32]
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(conversationKey, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(nonce)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), data[len(data)-32:]) {
+		return "", errors.New("nip44: invalid MAC")
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	padded := make([]byte, len(ciphertext))
+	cipher.XORKeyStream(padded, ciphertext)
+	return nip44Unpad(padded)
+}
+
+func nip44MessageKeys(conversationKey []byte, nonce []byte) ([]byte, []byte, []byte, error) {
+	keys := make([]byte, 76)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, conversationKey, nonce), keys); err != nil {
+		return nil, nil, nil, err
+	}
+	return keys[0:32], keys[32:44], keys[44:76], nil
+}
+
+func nip44PaddedLen(l int) int {
+	if l <= 32 {
+		return 32
+	}
+	nextPower := 1 << bits.Len(uint(l-1))
+	chunk := 32
+	if nextPower > 256 {
+		chunk = nextPower / 8
+	}
+	return chunk * ((l-1)/chunk + 1)
+}
+
+func nip44Pad(plaintext string) ([]byte, error) {
+	l := len(plaintext)
+	if l < NIP44_MIN_SIZE || l > NIP44_MAX_SIZE {
+		return nil, errors.New("nip44: invalid plaintext length")
+	}
+	padded := make([]byte, 2+nip44PaddedLen(l))
+	binary.BigEndian.PutUint16(padded, uint16(l))
+	copy(padded[2:], plaintext)
+	return padded, nil
+}
+
+func nip44Unpad(padded []byte) (string, error) {
+	if len(padded) < 2 {
+		return "", errors.New("nip44: invalid padding")
+	}
+	l := int(binary.BigEndian.Uint16(padded))
+	if l < NIP44_MIN_SIZE || l > NIP44_MAX_SIZE || len(padded) != 2+nip44PaddedLen(l) {
+		return "", errors.New("nip44: invalid padding")
+	}
+	return string(padded[2 : 2+l]), nil
+}
@@ -1,4 +1,5 @@
-package main
+// Package crypto holds the PIN encryption of the stored key and NIP-44.
+package crypto
 
 import (
 	"crypto/aes"
@@ -17,7 +18,7 @@ func createHash(key string) string {
 	return hex.EncodeToString(hasher.Sum(nil))
 }
 
-func encrypt(data []byte, passphrase string) ([]byte, error) {
+func Encrypt(data []byte, passphrase string) ([]byte, error) {
 	block, _ := aes.NewCipher([]byte(createHash(passphrase)))
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
@@ -31,7 +32,7 @@ func encrypt(data []byte, passphrase string) ([]byte, error) {
 	return ciphertext, nil
 }
 
-func decrypt(data []byte, passphrase string) ([]byte, error) {
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
 	key := []byte(createHash(passphrase))
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -50,15 +51,15 @@ func decrypt(data []byte, passphrase string) ([]byte, error) {
 	return plaintext, err
 }
 
-func encryptFile(filename string, data []byte, passphrase string) {
+func EncryptFile(filename string, data []byte, passphrase string) {
 	f, _ := os.Create(filename)
 	defer f.Close()
-	d, _ := encrypt(data, passphrase)
+	d, _ := Encrypt(data, passphrase)
 	f.Write(d)
 }
 
-func decryptFile(filename string, passphrase string) []byte {
+func DecryptFile(filename string, passphrase string) []byte {
 	data, _ := ioutil.ReadFile(filename)
-	d, _ := decrypt(data, passphrase)
+	d, _ := Decrypt(data, passphrase)
 	return d
 }
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"regexp"
+	"strings"
+)
+
+// NIP-23 long-form content
+const (
+	KIND_ARTICLE       = 30023
+	KIND_ARTICLE_DRAFT = 30024
+)
+
+var slugRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+type Article struct {
+	Id          string          `json:"id"`
+	PubKey      string          `json:"pubkey"`
+	Kind        int             `json:"kind"`
+	Identifier  string          `json:"identifier"`
+	Title       string          `json:"title"`
+	Summary     string          `json:"summary"`
+	Image       string          `json:"image"`
+	PublishedAt int64           `json:"publishedAt"`
+	CreatedAt   nostr.Timestamp `json:"created_at"`
+	Hashtags    []string        `json:"hashtags"`
+	Content     string          `json:"content"`
+	Naddr       string          `json:"naddr"`
+}
+
+func IsArticleKind(kind int) bool {
+	return kind == KIND_ARTICLE || kind == KIND_ARTICLE_DRAFT
+}
+
+func TagValue(tags nostr.Tags, key string) string {
+	tag := tags.GetFirst([]string{key, ""})
+	if tag == nil {
+		return ""
+	}
+	return tag.Value()
+}
+
+// Slugify makes a "d" identifier for a new article from its title
+func Slugify(title string) string {
+	slug := strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	return fmt.Sprintf("%s-%d", slug, nostr.Now())
+}
@@ -0,0 +1,27 @@
+package domain
+
+type Bookmark struct {
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Private bool   `json:"private"`
+}
+
+type BookmarkList struct {
+	Kind  int         `json:"kind"`
+	Name  string      `json:"name"`
+	Title string      `json:"title"`
+	Items []*Bookmark `json:"items"`
+}
+
+func IsBookmarkType(t string) bool {
+	return t == "e" || t == "a" || t == "t" || t == "r"
+}
+
+// BookmarkListKind maps a set name to its list kind. The blank name is the
+// standard kind-10003 bookmark list, anything else a kind-30003 set.
+func BookmarkListKind(name string) int {
+	if name == "" {
+		return KIND_BOOKMARK_LIST
+	}
+	return KIND_BOOKMARK_SET
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+)
+
+type ContactListVersion struct {
+	Id        string          `json:"id"`
+	CreatedAt nostr.Timestamp `json:"created_at"`
+	Count     int             `json:"count"`
+}
+
+type ContactListDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Before  int      `json:"before"`
+	After   int      `json:"after"`
+}
+
+func ContactPubkeys(tags nostr.Tags) []string {
+	pks := []string{}
+	for _, tag := range tags.GetAll([]string{"p", ""}) {
+		if !Contains(pks, tag.Value()) {
+			pks = append(pks, tag.Value())
+		}
+	}
+	return pks
+}
+
+func DiffContacts(before []string, after []string) *ContactListDiff {
+	diff := ContactListDiff{
+		Added:   []string{},
+		Removed: []string{},
+		Before:  len(before),
+		After:   len(after),
+	}
+	for _, pk := range after {
+		if !Contains(before, pk) {
+			diff.Added = append(diff.Added, pk)
+		}
+	}
+	for _, pk := range before {
+		if !Contains(after, pk) {
+			diff.Removed = append(diff.Removed, pk)
+		}
+	}
+	return &diff
+}
+
+// IsSuspiciousContactCount reports whether a contact list has lost so many
+// entries compared to the last one we knew about that it was probably wiped
+// by a misbehaving client, or has not fully loaded
+func IsSuspiciousContactCount(count int, lastKnown int) bool {
+	return lastKnown > 0 && count < lastKnown/2
+}
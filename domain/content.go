@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	TOKEN_TEXT    = "text"
+	TOKEN_URL     = "url"
+	TOKEN_MEDIA   = "media"
+	TOKEN_HASHTAG = "hashtag"
+	TOKEN_NOSTR   = "nostr"
+	TOKEN_INDEX   = "index"
+)
+
+var mediaTypes = map[string]string{
+	".jpg":  "image",
+	".jpeg": "image",
+	".png":  "image",
+	".gif":  "image",
+	".webp": "image",
+	".svg":  "image",
+	".mp4":  "video",
+	".webm": "video",
+	".mov":  "video",
+	".mp3":  "audio",
+	".ogg":  "audio",
+	".wav":  "audio",
+	".m4a":  "audio",
+}
+
+// One alternative per token type, tried left to right. Hashtags are checked
+// separately for a word boundary since RE2 has no lookbehind.
+var contentRegex = regexp.MustCompile(`https?://[^\s<>"]+` +
+	`|nostr:(?:npub|nprofile|note|nevent|naddr)1[02-9ac-hj-np-z]+` +
+	`|#\[\d+\]` +
+	`|#[\p{L}\p{N}_]+`)
+
+type ContentToken struct {
+	Type      string       `json:"type"`
+	Text      string       `json:"text"`
+	Value     string       `json:"value"`
+	MediaType string       `json:"mediaType,omitempty"`
+	Entity    *Nip19Entity `json:"entity,omitempty"`
+	Profile   *Profile     `json:"profile,omitempty"`
+	Event     *nostr.Event `json:"event,omitempty"`
+}
+
+// ParseContent splits note content into tokens. Legacy #[n] references are
+// resolved against tags; nothing is fetched.
+func ParseContent(content string, tags nostr.Tags) []*ContentToken {
+	tokens := []*ContentToken{}
+	addText := func(text string) {
+		if text == "" {
+			return
+		}
+		if n := len(tokens); n > 0 && tokens[n-1].Type == TOKEN_TEXT {
+			tokens[n-1].Text += text
+			tokens[n-1].Value += text
+			return
+		}
+		tokens = append(tokens, &ContentToken{Type: TOKEN_TEXT, Text: text, Value: text})
+	}
+
+	last := 0
+	for _, loc := range contentRegex.FindAllStringIndex(content, -1) {
+		start, end := loc[0], loc[1]
+		if start < last {
+			continue
+		}
+		match := content[start:end]
+		var token *ContentToken
+
+		switch {
+		case strings.HasPrefix(match, "http"):
+			// Trailing punctuation is almost always part of the sentence
+			trimmed := strings.TrimRight(match, ".,;:!?)]}'")
+			end = start + len(trimmed)
+			token = parseUrlToken(trimmed)
+		case strings.HasPrefix(match, "nostr:"):
+			entity, err := DecodeNip19(match)
+			if err != nil {
+				log.Debug().Msgf("Bad nostr URI in content %s: %s", match, err.Error())
+				break
+			}
+			token = &ContentToken{Type: TOKEN_NOSTR, Text: match, Value: EntityRef(entity), Entity: entity}
+		case strings.HasPrefix(match, "#["):
+			n, _ := strconv.Atoi(match[2 : len(match)-1])
+			if n >= len(tags) || len(tags[n]) < 2 {
+				break
+			}
+			token = &ContentToken{Type: TOKEN_INDEX, Text: match, Value: tags[n].Value()}
+			switch tags[n].Key() {
+			case "p":
+				token.Entity = &Nip19Entity{Type: "npub", PubKey: tags[n].Value(), Relays: []string{}}
+			case "e":
+				token.Entity = &Nip19Entity{Type: "note", Id: tags[n].Value(), Relays: []string{}}
+			}
+			if token.Entity != nil && tags[n].Relay() != "" {
+				token.Entity.Relays = append(token.Entity.Relays, tags[n].Relay())
+			}
+		default:
+			if start > 0 {
+				prev := []rune(content[:start])
+				if r := prev[len(prev)-1]; unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '&' {
+					break
+				}
+			}
+			token = &ContentToken{Type: TOKEN_HASHTAG, Text: match, Value: strings.ToLower(match[1:])}
+		}
+
+		if token == nil {
+			continue
+		}
+		addText(content[last:start])
+		tokens = append(tokens, token)
+		last = end
+	}
+	addText(content[last:])
+	return tokens
+}
+
+func parseUrlToken(raw string) *ContentToken {
+	token := ContentToken{Type: TOKEN_URL, Text: raw, Value: raw}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return &token
+	}
+	if mediaType, ok := mediaTypes[strings.ToLower(path.Ext(u.Path))]; ok {
+		token.Type = TOKEN_MEDIA
+		token.MediaType = mediaType
+	}
+	return &token
+}
+
+// EntityRef is the hex id, pubkey or kind:pubkey:d address a NIP-19 entity
+// points at
+func EntityRef(entity *Nip19Entity) string {
+	switch entity.Type {
+	case "npub", "nprofile":
+		return entity.PubKey
+	case "naddr":
+		return fmt.Sprintf("%d:%s:%s", entity.Kind, entity.PubKey, entity.Identifier)
+	}
+	return entity.Id
+}
+
+// HasTag reports whether a tag with the same key and value is already there,
+// whatever relay hint or marker it carries
+func HasTag(tags nostr.Tags, key string, value string) bool {
+	return tags.GetFirst([]string{key, value}) != nil
+}
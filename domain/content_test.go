@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"testing"
+)
+
+const testPubkey = "3bf0c63fcb93463407af97a5e5ee64fa883d107ef9e558472c4eb9aaaefa459d"
+
+func TestParseContent(t *testing.T) {
+	npub, _ := nip19.EncodePublicKey(testPubkey)
+	naddr, _ := nip19.EncodeEntity(testPubkey, KIND_ARTICLE, "post", []string{"wss://relay.example.com"})
+
+	type want struct{ typ, text, value string }
+	tests := []struct {
+		name    string
+		content string
+		tags    nostr.Tags
+		want    []want
+	}{
+		{
+			name:    "url with trailing punctuation",
+			content: "see https://example.com/a?b=1.",
+			want:    []want{{TOKEN_TEXT, "see ", "see "}, {TOKEN_URL, "https://example.com/a?b=1", "https://example.com/a?b=1"}, {TOKEN_TEXT, ".", "."}},
+		},
+		{
+			name:    "media",
+			content: "https://example.com/cat.JPG",
+			want:    []want{{TOKEN_MEDIA, "https://example.com/cat.JPG", "https://example.com/cat.JPG"}},
+		},
+		{
+			name:    "hashtags",
+			content: "#Nostr and a#b &#39; #go_1",
+			want: []want{
+				{TOKEN_HASHTAG, "#Nostr", "nostr"},
+				{TOKEN_TEXT, " and a#b &#39; ", " and a#b &#39; "},
+				{TOKEN_HASHTAG, "#go_1", "go_1"},
+			},
+		},
+		{
+			name:    "npub",
+			content: "hi nostr:" + npub + "!",
+			want:    []want{{TOKEN_TEXT, "hi ", "hi "}, {TOKEN_NOSTR, "nostr:" + npub, testPubkey}, {TOKEN_TEXT, "!", "!"}},
+		},
+		{
+			name:    "naddr",
+			content: "nostr:" + naddr,
+			want:    []want{{TOKEN_NOSTR, "nostr:" + naddr, "30023:" + testPubkey + ":post"}},
+		},
+		{
+			name:    "bad nostr uri stays text",
+			content: "nostr:npub1qqqq",
+			want:    []want{{TOKEN_TEXT, "nostr:npub1qqqq", "nostr:npub1qqqq"}},
+		},
+		{
+			name:    "index",
+			content: "cc #[1] and #[5]",
+			tags:    nostr.Tags{{"e", "ee"}, {"p", testPubkey, "wss://relay.example.com"}},
+			want:    []want{{TOKEN_TEXT, "cc ", "cc "}, {TOKEN_INDEX, "#[1]", testPubkey}, {TOKEN_TEXT, " and #[5]", " and #[5]"}},
+		},
+	}
+	for _, tt := range tests {
+		tokens := ParseContent(tt.content, tt.tags)
+		if len(tokens) != len(tt.want) {
+			t.Errorf("%s: %d tokens, want %d", tt.name, len(tokens), len(tt.want))
+			continue
+		}
+		for i, w := range tt.want {
+			got := tokens[i]
+			if got.Type != w.typ || got.Text != w.text || got.Value != w.value {
+				t.Errorf("%s: token %d is %s %q %q, want %s %q %q", tt.name, i, got.Type, got.Text, got.Value, w.typ, w.text, w.value)
+			}
+		}
+	}
+}
+
+func TestParseContentIndexEntity(t *testing.T) {
+	tokens := ParseContent("#[0]", nostr.Tags{{"p", testPubkey, "wss://relay.example.com"}})
+	entity := tokens[0].Entity
+	if entity == nil || entity.Type != "npub" || entity.PubKey != testPubkey {
+		t.Fatalf("entity %+v", entity)
+	}
+	if len(entity.Relays) != 1 || entity.Relays[0] != "wss://relay.example.com" {
+		t.Errorf("relay hint %v", entity.Relays)
+	}
+}
@@ -0,0 +1,45 @@
+package domain
+
+// FEED_CONTACTS is the feed built from the kind-3 contact list. Any other
+// feed ID is the "d" tag of one of our kind-30000 follow sets.
+const FEED_CONTACTS = "contacts"
+
+type FollowSet struct {
+	Name    string   `json:"name"`
+	Title   string   `json:"title"`
+	Pubkeys []string `json:"pubkeys"`
+	Private []string `json:"private"`
+}
+
+type Feed struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	Size  int    `json:"size"`
+}
+
+func (s *FollowSet) Members() []string {
+	members := []string{}
+	for _, pk := range append(s.Pubkeys, s.Private...) {
+		if !Contains(members, pk) {
+			members = append(members, pk)
+		}
+	}
+	return members
+}
+
+func (s *FollowSet) Remove(pk string) {
+	pubkeys := []string{}
+	for _, p := range s.Pubkeys {
+		if p != pk {
+			pubkeys = append(pubkeys, p)
+		}
+	}
+	private := []string{}
+	for _, p := range s.Private {
+		if p != pk {
+			private = append(private, p)
+		}
+	}
+	s.Pubkeys = pubkeys
+	s.Private = private
+}
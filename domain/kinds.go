@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// NIP-01: only the newest replaceable event is kept per pubkey and kind, and
+// the newest addressable event per pubkey, kind and "d" tag
+func IsReplaceableKind(kind int) bool {
+	return kind == nostr.KindSetMetadata || kind == nostr.KindContactList || (kind >= 10000 && kind < 20000)
+}
+
+func IsAddressableKind(kind int) bool {
+	return kind >= 30000 && kind < 40000
+}
+
+// EventAddress is the kind:pubkey:d key a replaceable or addressable event
+// replaces under. Replaceable events have a blank d.
+func EventAddress(ev *nostr.Event) string {
+	d := ""
+	if IsAddressableKind(ev.Kind) {
+		if tag := ev.Tags.GetFirst([]string{"d", ""}); tag != nil {
+			d = tag.Value()
+		}
+	}
+	return fmt.Sprintf("%d:%s:%s", ev.Kind, ev.PubKey, d)
+}
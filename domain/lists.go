@@ -0,0 +1,63 @@
+package domain
+
+import "github.com/nbd-wtf/go-nostr"
+
+// NIP-51 list kinds
+const (
+	KIND_MUTE_LIST     = 10000
+	KIND_BOOKMARK_LIST = 10003
+	KIND_FOLLOW_SET    = 30000
+	KIND_BOOKMARK_SET  = 30003
+)
+
+// ReplaceListItems edits a copy of a list's tags to hold items. The tags
+// managed picks out are replaced by items, in their order, after all the
+// others; an item that was already there keeps its extra fields. Any other
+// tag, ours or from another client, is kept as it was.
+func ReplaceListItems(tags nostr.Tags, managed func(tag nostr.Tag) bool, items nostr.Tags) nostr.Tags {
+	out := nostr.Tags{}
+	old := map[[2]string]nostr.Tag{}
+	for _, tag := range tags {
+		if !managed(tag) {
+			out = append(out, append(nostr.Tag{}, tag...))
+		} else if _, ok := old[[2]string{tag.Key(), tag.Value()}]; !ok {
+			old[[2]string{tag.Key(), tag.Value()}] = tag
+		}
+	}
+	seen := map[[2]string]bool{}
+	for _, item := range items {
+		key := [2]string{item.Key(), item.Value()}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if tag, ok := old[key]; ok {
+			item = tag
+		}
+		out = append(out, append(nostr.Tag{}, item...))
+	}
+	return out
+}
+
+// SetTagValue edits a copy of tags to have one key tag with value, in place
+// of the first there was. A blank value drops them all.
+func SetTagValue(tags nostr.Tags, key string, value string) nostr.Tags {
+	out := nostr.Tags{}
+	set := false
+	for _, tag := range tags {
+		if tag.Key() != key {
+			out = append(out, append(nostr.Tag{}, tag...))
+		} else if value != "" && !set {
+			edited := nostr.Tag{key, value}
+			if len(tag) > 2 {
+				edited = append(edited, tag[2:]...)
+			}
+			out = append(out, edited)
+			set = true
+		}
+	}
+	if value != "" && !set {
+		out = append(out, nostr.Tag{key, value})
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/nbd-wtf/go-nostr"
+	"strings"
+	"sync"
+)
+
+const (
+	MUTE_PUBKEY  = "p"
+	MUTE_HASHTAG = "t"
+	MUTE_WORD    = "word"
+	MUTE_THREAD  = "e"
+)
+
+type MuteEntry struct {
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Private bool   `json:"private"`
+}
+
+type MuteList struct {
+	entries []*MuteEntry
+	mu      sync.Mutex
+}
+
+func NewMuteList() *MuteList {
+	return &MuteList{
+		entries: []*MuteEntry{},
+	}
+}
+
+func IsMuteType(t string) bool {
+	return t == MUTE_PUBKEY || t == MUTE_HASHTAG || t == MUTE_WORD || t == MUTE_THREAD
+}
+
+// ParseMuteTags reads the entries of a mute list from its public tags and
+// decrypted private tags. Tags that are not mutes come back unchanged, so an
+// edit can carry them through.
+func ParseMuteTags(public, private nostr.Tags) ([]*MuteEntry, nostr.Tags, nostr.Tags) {
+	entries := []*MuteEntry{}
+	otherPublic, otherPrivate := nostr.Tags{}, nostr.Tags{}
+	for _, tag := range public {
+		if IsMuteType(tag.Key()) && tag.Value() != "" {
+			entries = append(entries, &MuteEntry{Type: tag.Key(), Value: tag.Value()})
+		} else {
+			otherPublic = append(otherPublic, tag)
+		}
+	}
+	for _, tag := range private {
+		if IsMuteType(tag.Key()) && tag.Value() != "" {
+			entries = append(entries, &MuteEntry{Type: tag.Key(), Value: tag.Value(), Private: true})
+		} else {
+			otherPrivate = append(otherPrivate, tag)
+		}
+	}
+	return entries, otherPublic, otherPrivate
+}
+
+// MuteTags is the reverse of ParseMuteTags
+func MuteTags(entries []*MuteEntry, otherPublic, otherPrivate nostr.Tags) (nostr.Tags, nostr.Tags) {
+	public := append(nostr.Tags{}, otherPublic...)
+	private := append(nostr.Tags{}, otherPrivate...)
+	for _, e := range entries {
+		if e.Private {
+			private = append(private, nostr.Tag{e.Type, e.Value})
+		} else {
+			public = append(public, nostr.Tag{e.Type, e.Value})
+		}
+	}
+	return public, private
+}
+
+func (m *MuteList) Set(entries []*MuteEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = entries
+}
+
+func (m *MuteList) Entries() []*MuteEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]*MuteEntry, len(m.entries))
+	copy(entries, m.entries)
+	return entries
+}
+
+// IsMuted checks the author, thread, hashtags and content of an event against
+// the mute list. Reposts are checked against the reposted note too.
+func (m *MuteList) IsMuted(ev *nostr.Event) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.matches(ev) {
+		return true
+	}
+	if ev.Kind == nostr.KindBoost && ev.Content != "" {
+		var reposted nostr.Event
+		if json.Unmarshal([]byte(ev.Content), &reposted) == nil && m.matches(&reposted) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MuteList) IsMutedPubkey(pk string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.entries {
+		if e.Type == MUTE_PUBKEY && e.Value == pk {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MuteList) matches(ev *nostr.Event) bool {
+	content := strings.ToLower(ev.Content)
+	for _, e := range m.entries {
+		switch e.Type {
+		case MUTE_PUBKEY:
+			if ev.PubKey == e.Value {
+				return true
+			}
+		case MUTE_THREAD:
+			if ev.ID == e.Value || ev.Tags.ContainsAny("e", []string{e.Value}) {
+				return true
+			}
+		case MUTE_HASHTAG:
+			for _, tag := range ev.Tags.GetAll([]string{"t"}) {
+				if strings.EqualFold(tag.Value(), e.Value) {
+					return true
+				}
+			}
+		case MUTE_WORD:
+			if e.Value != "" && strings.Contains(content, strings.ToLower(e.Value)) {
+				return true
+			}
+		}
+	}
+	return false
+}
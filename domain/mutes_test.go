@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"reflect"
+	"testing"
+)
+
+func TestMuteTagsRoundTrip(t *testing.T) {
+	public := nostr.Tags{
+		{"p", "aa"},
+		{"client", "other"},
+		{"t", "spam"},
+	}
+	private := nostr.Tags{
+		{"word", "noise"},
+		{"emoji", ":x:", "https://example.com/x.png"},
+	}
+
+	entries, otherPublic, otherPrivate := ParseMuteTags(public, private)
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if !entries[2].Private || entries[2].Value != "noise" {
+		t.Errorf("private entry not read: %+v", entries[2])
+	}
+
+	gotPublic, gotPrivate := MuteTags(entries, otherPublic, otherPrivate)
+	wantPublic := nostr.Tags{{"client", "other"}, {"p", "aa"}, {"t", "spam"}}
+	wantPrivate := nostr.Tags{{"emoji", ":x:", "https://example.com/x.png"}, {"word", "noise"}}
+	if !reflect.DeepEqual(gotPublic, wantPublic) {
+		t.Errorf("public tags %v, want %v", gotPublic, wantPublic)
+	}
+	if !reflect.DeepEqual(gotPrivate, wantPrivate) {
+		t.Errorf("private tags %v, want %v", gotPrivate, wantPrivate)
+	}
+}
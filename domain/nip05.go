@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// IsNip05Identifier tells a name@domain (or bare domain) identifier apart
+// from hex and bech32 keys
+func IsNip05Identifier(s string) bool {
+	if s == "" || strings.ContainsAny(s, " /:") {
+		return false
+	}
+	return strings.Contains(s, "@") || strings.Contains(s, ".")
+}
+
+func SplitNip05(identifier string) (string, string, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(identifier)), "@")
+	switch len(parts) {
+	case 1:
+		return "_", parts[0], nil
+	case 2:
+		if parts[0] == "" {
+			return "_", parts[1], nil
+		}
+		return parts[0], parts[1], nil
+	}
+	return "", "", errors.New("Not a valid NIP-05 identifier: " + identifier)
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"strings"
+)
+
+type Nip19Entity struct {
+	Type       string   `json:"type"`
+	PubKey     string   `json:"pubkey"`
+	PrivKey    string   `json:"privkey"`
+	Id         string   `json:"id"`
+	Kind       int      `json:"kind"`
+	Identifier string   `json:"identifier"`
+	Relays     []string `json:"relays"`
+}
+
+func DecodeNip19(uri string) (*Nip19Entity, error) {
+	uri = strings.TrimPrefix(strings.TrimSpace(uri), "nostr:")
+	prefix, val, err := nip19.Decode(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := Nip19Entity{
+		Type:   prefix,
+		Relays: []string{},
+	}
+	switch v := val.(type) {
+	case string:
+		switch prefix {
+		case "npub":
+			entity.PubKey = v
+		case "nsec":
+			entity.PrivKey = v
+		case "note":
+			entity.Id = v
+		}
+	case nostr.EventPointer:
+		entity.Id = v.ID
+		entity.PubKey = v.Author
+		entity.Kind = v.Kind
+		entity.Relays = append(entity.Relays, v.Relays...)
+	case nostr.ProfilePointer:
+		entity.PubKey = v.PublicKey
+		entity.Relays = append(entity.Relays, v.Relays...)
+	case nostr.EntityPointer:
+		entity.PubKey = v.PublicKey
+		entity.Kind = v.Kind
+		entity.Identifier = v.Identifier
+		entity.Relays = append(entity.Relays, v.Relays...)
+	default:
+		return nil, errors.New("Unsupported NIP-19 entity: " + prefix)
+	}
+	return &entity, nil
+}
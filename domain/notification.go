@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const (
+	NOTIFY_MENTION  = "mention"
+	NOTIFY_REPLY    = "reply"
+	NOTIFY_REACTION = "reaction"
+	NOTIFY_REPOST   = "repost"
+	NOTIFY_ZAP      = "zap"
+	NOTIFY_FOLLOWER = "follower"
+)
+
+type Notification struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	From      string          `json:"from"`
+	CreatedAt nostr.Timestamp `json:"created_at"`
+	Read      bool            `json:"read"`
+	Event     *nostr.Event    `json:"event"`
+}
+
+// ClassifyNotification works out why an event tagging our pubkey was sent.
+// Returns nil for events that should not raise a notification.
+func ClassifyNotification(ev *nostr.Event, pk string) *Notification {
+	if ev.PubKey == pk {
+		return nil
+	}
+
+	n := Notification{
+		Id:        ev.ID,
+		From:      ev.PubKey,
+		CreatedAt: ev.CreatedAt,
+		Event:     ev,
+	}
+
+	switch ev.Kind {
+	case nostr.KindTextNote:
+		if ReplyToId(ev) != "" {
+			n.Type = NOTIFY_REPLY
+		} else {
+			n.Type = NOTIFY_MENTION
+		}
+	case nostr.KindReaction:
+		n.Type = NOTIFY_REACTION
+	case nostr.KindBoost:
+		n.Type = NOTIFY_REPOST
+	case nostr.KindZap:
+		n.Type = NOTIFY_ZAP
+		// The receipt is signed by the LN service, the sender is in the zap request
+		if desc := ev.Tags.GetFirst([]string{"description", ""}); desc != nil {
+			var req nostr.Event
+			if json.Unmarshal([]byte(desc.Value()), &req) == nil && req.PubKey != "" {
+				n.From = req.PubKey
+			}
+		}
+	case nostr.KindContactList:
+		n.Type = NOTIFY_FOLLOWER
+		n.Id = ev.PubKey // One per follower, not per contact list version
+	default:
+		return nil
+	}
+	return &n
+}
+
+// IsNewFollow tells whether a contact list is the one that adds pk. Against
+// the follower's previous list that is whether pk was missing from it.
+// Without one, pk must be the last contact, where clients add a new follow.
+func IsNewFollow(prev *nostr.Event, ev *nostr.Event, pk string) bool {
+	if !Contains(ContactPubkeys(ev.Tags), pk) {
+		return false
+	}
+	if prev != nil {
+		return prev.CreatedAt < ev.CreatedAt && !Contains(ContactPubkeys(prev.Tags), pk)
+	}
+	last := ""
+	for _, tag := range ev.Tags.GetAll([]string{"p", ""}) {
+		last = tag.Value()
+	}
+	return last == pk
+}
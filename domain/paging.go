@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"sort"
+)
+
+// PageEvents cuts the newest count events, newest first, from events all
+// created before some until. Events at or below floor may be incomplete, as
+// a relay hit its limit there, so they are left for the next page; a floor
+// of 0 means the events are complete. The page runs past count to the end of
+// a second, so a second is never split between pages. Next is the until for
+// the following page, 0 when nothing older remains.
+func PageEvents(events []*nostr.Event, count int, floor nostr.Timestamp) ([]*nostr.Event, nostr.Timestamp) {
+	sorted := make([]*nostr.Event, len(events))
+	copy(sorted, events)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt > sorted[j].CreatedAt
+	})
+
+	above := 0
+	for above < len(sorted) && sorted[above].CreatedAt > floor {
+		above++
+	}
+	candidates := sorted[:above]
+	if above == 0 && floor > 0 {
+		// More events in the floor's second than a relay returns, take them
+		// and move on rather than stall
+		for above < len(sorted) && sorted[above].CreatedAt == floor {
+			above++
+		}
+		candidates = sorted[:above]
+	}
+
+	n := count
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	for n > 0 && n < len(candidates) && candidates[n].CreatedAt == candidates[n-1].CreatedAt {
+		n++
+	}
+	page := candidates[:n]
+
+	if floor == 0 && n == len(candidates) {
+		return page, 0
+	}
+	if n == 0 {
+		return page, floor - 1
+	}
+	return page, page[n-1].CreatedAt - 1
+}
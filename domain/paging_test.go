@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"reflect"
+	"testing"
+)
+
+// eventsAt makes an event per timestamp, with ids telling them apart
+func eventsAt(times ...nostr.Timestamp) []*nostr.Event {
+	events := []*nostr.Event{}
+	for i, ts := range times {
+		events = append(events, &nostr.Event{ID: fmt.Sprintf("%d-%d", ts, i), CreatedAt: ts})
+	}
+	return events
+}
+
+func timesOf(events []*nostr.Event) []nostr.Timestamp {
+	times := []nostr.Timestamp{}
+	for _, ev := range events {
+		times = append(times, ev.CreatedAt)
+	}
+	return times
+}
+
+func TestPageEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*nostr.Event
+		count  int
+		floor  nostr.Timestamp
+		page   []nostr.Timestamp
+		next   nostr.Timestamp
+	}{
+		{
+			name:   "empty",
+			events: eventsAt(),
+			count:  3,
+			page:   []nostr.Timestamp{},
+		},
+		{
+			name:   "all fit",
+			events: eventsAt(5, 9, 7),
+			count:  3,
+			page:   []nostr.Timestamp{9, 7, 5},
+		},
+		{
+			name:   "more remain",
+			events: eventsAt(5, 9, 7, 3),
+			count:  2,
+			page:   []nostr.Timestamp{9, 7},
+			next:   6,
+		},
+		{
+			name:   "second not split",
+			events: eventsAt(10, 9, 9, 9, 8),
+			count:  2,
+			page:   []nostr.Timestamp{10, 9, 9, 9},
+			next:   8,
+		},
+		{
+			name:   "floor holds back",
+			events: eventsAt(10, 9, 8, 7),
+			count:  10,
+			floor:  8,
+			page:   []nostr.Timestamp{10, 9},
+			next:   8,
+		},
+		{
+			name:   "nothing above floor",
+			events: eventsAt(8, 8, 7),
+			count:  10,
+			floor:  8,
+			page:   []nostr.Timestamp{8, 8},
+			next:   7,
+		},
+		{
+			name:   "nothing at all below until",
+			events: eventsAt(),
+			count:  10,
+			floor:  8,
+			page:   []nostr.Timestamp{},
+			next:   7,
+		},
+	}
+	for _, tt := range tests {
+		page, next := PageEvents(tt.events, tt.count, tt.floor)
+		if got := timesOf(page); !reflect.DeepEqual(got, tt.page) {
+			t.Errorf("%s: page %v, want %v", tt.name, got, tt.page)
+		}
+		if next != tt.next {
+			t.Errorf("%s: next %d, want %d", tt.name, next, tt.next)
+		}
+	}
+}
+
+// Following the cursors visits every event once, newest first
+func TestPageEventsCursors(t *testing.T) {
+	all := eventsAt(1, 2, 2, 3, 4, 4, 4, 5, 6, 7, 7, 8)
+	seen := map[string]bool{}
+	until := nostr.Timestamp(100)
+	for pages := 0; ; pages++ {
+		if pages > len(all) {
+			t.Fatal("Cursors do not reach the end")
+		}
+		below := []*nostr.Event{}
+		for _, ev := range all {
+			if ev.CreatedAt <= until {
+				below = append(below, ev)
+			}
+		}
+		page, next := PageEvents(below, 3, 0)
+		for _, ev := range page {
+			if seen[ev.ID] {
+				t.Errorf("Event %s on two pages", ev.ID)
+			}
+			seen[ev.ID] = true
+		}
+		if next == 0 {
+			break
+		}
+		until = next
+	}
+	if len(seen) != len(all) {
+		t.Errorf("Visited %d events, want %d", len(seen), len(all))
+	}
+}
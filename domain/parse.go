@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodePubkey turns a hex key, npub or nprofile into a hex public key.
+// NIP-05 identifiers need a lookup, see Client.ResolvePubkey.
+func DecodePubkey(user string) (string, error) {
+	if strings.HasPrefix(user, "npub") || strings.HasPrefix(user, "nprofile") || strings.HasPrefix(user, "nostr:") {
+		entity, err := DecodeNip19(user)
+		if err != nil {
+			return "", err
+		}
+		if entity.PubKey == "" {
+			return "", errors.New("Not a profile: " + user)
+		}
+		return entity.PubKey, nil
+	}
+	if nostr.IsValidPublicKeyHex(user) {
+		return user, nil
+	}
+	return "", errors.New("Not a public key, npub or NIP-05 identifier: " + user)
+}
+
+// ParseSince reads a since value, either a duration back from now such as
+// 90m, 6h or 2d, or a unix timestamp
+func ParseSince(s string) (nostr.Timestamp, error) {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return nostr.Timestamp(ts), nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("Bad duration %s", s)
+		}
+		return nostr.Now() - nostr.Timestamp(days*24*60*60), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("Bad duration %s", s)
+	}
+	return nostr.Now() - nostr.Timestamp(d.Seconds()), nil
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const NIP05_TTL = time.Hour
+
+type Profile struct {
+	Pk             string          `json:"pk"`
+	Following      bool            `json:"following"`
+	Meta           ProfileMetadata `json:"meta"`
+	Npub           string          `json:"npub"`
+	Relays         []string        `json:"relays"`
+	Nip05Verified  bool            `json:"nip05Verified"`
+	Nip05CheckedAt int64           `json:"nip05CheckedAt"`
+}
+
+func NewProfile() Profile {
+	return Profile{
+		Pk:        "",
+		Npub:      "",
+		Following: false,
+		Meta:      ProfileMetadata{},
+		Relays:    []string{},
+	}
+}
+
+func NewProfileFromJson(j string) Profile {
+	profile := NewProfile()
+	err := json.Unmarshal([]byte(j), &profile)
+	if err != nil {
+		log.Error().Msgf("NewProfileFromJson: %s", err.Error())
+	}
+	return profile
+}
+
+func (p *Profile) ToJson() string {
+	j, _ := json.Marshal(p)
+	return string(j)
+}
+
+// Nip05Expired reports whether the NIP-05 verification result needs refreshing
+func (p *Profile) Nip05Expired() bool {
+	return time.Since(time.Unix(p.Nip05CheckedAt, 0)) > NIP05_TTL
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip10"
+	"sort"
+)
+
+const MAX_THREAD_DEPTH = 50
+
+type ThreadNode struct {
+	Event   *nostr.Event  `json:"event"`
+	Replies []*ThreadNode `json:"replies"`
+}
+
+type Thread struct {
+	RootId    string         `json:"rootId"`
+	FocusId   string         `json:"focusId"`
+	Ancestors []*nostr.Event `json:"ancestors"`
+	Root      *ThreadNode    `json:"root"`
+}
+
+// ThreadRootId returns the root of the thread the event belongs to, using
+// the NIP-10 "root" marker or, for unmarked tags, the first "e" tag that is
+// not a mention
+func ThreadRootId(ev *nostr.Event) string {
+	tag := nip10.GetThreadRoot(ev.Tags)
+	if tag == nil {
+		return ""
+	}
+	if len(*tag) >= 4 && (*tag)[3] == "mention" {
+		for _, t := range ev.Tags.GetAll([]string{"e", ""}) {
+			if len(t) < 4 || t[3] != "mention" {
+				return t.Value()
+			}
+		}
+		return ""
+	}
+	return tag.Value()
+}
+
+// ReplyToId returns the event being replied to, using the NIP-10 "reply"
+// marker, then the "root" marker of a direct reply, then for unmarked tags
+// the last "e" tag that is not a mention
+func ReplyToId(ev *nostr.Event) string {
+	tag := ReplyTag(ev.Tags)
+	if tag == nil {
+		return ""
+	}
+	return tag.Value()
+}
+
+// ReplyTag is the "e" tag ReplyToId reads the parent from
+func ReplyTag(tags nostr.Tags) *nostr.Tag {
+	var root, last *nostr.Tag
+	for i := range tags {
+		tag := tags[i]
+		if len(tag) < 2 || tag[0] != "e" {
+			continue
+		}
+		marker := ""
+		if len(tag) >= 4 {
+			marker = tag[3]
+		}
+		switch marker {
+		case "reply":
+			return &tag
+		case "root":
+			if root == nil {
+				root = &tag
+			}
+		case "mention":
+		default:
+			last = &tag
+		}
+	}
+	if root != nil {
+		return root
+	}
+	return last
+}
+
+// BuildThreadTree arranges replies under their immediate parents, oldest
+// first. Replies whose parent could not be found are attached to the root.
+func BuildThreadTree(root *nostr.Event, replies []*nostr.Event) *ThreadNode {
+	rootNode := &ThreadNode{Event: root, Replies: []*ThreadNode{}}
+	nodes := map[string]*ThreadNode{root.ID: rootNode}
+
+	for _, ev := range replies {
+		if _, ok := nodes[ev.ID]; !ok {
+			nodes[ev.ID] = &ThreadNode{Event: ev, Replies: []*ThreadNode{}}
+		}
+	}
+	for id, node := range nodes {
+		if id == root.ID {
+			continue
+		}
+		parent, ok := nodes[ReplyToId(node.Event)]
+		if !ok || parent == node {
+			parent = rootNode
+		}
+		parent.Replies = append(parent.Replies, node)
+	}
+	for _, node := range nodes {
+		sort.Slice(node.Replies, func(i, j int) bool {
+			return node.Replies[i].Event.CreatedAt < node.Replies[j].Event.CreatedAt
+		})
+	}
+	return rootNode
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"testing"
+)
+
+func TestThreadMarkers(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  nostr.Tags
+		root  string
+		reply string
+	}{
+		{
+			name: "none",
+			tags: nostr.Tags{{"p", "pk"}},
+		},
+		{
+			name:  "marked reply",
+			tags:  nostr.Tags{{"e", "root", "", "root"}, {"e", "parent", "", "reply"}},
+			root:  "root",
+			reply: "parent",
+		},
+		{
+			name:  "marked reply first",
+			tags:  nostr.Tags{{"e", "parent", "", "reply"}, {"e", "root", "", "root"}},
+			root:  "root",
+			reply: "parent",
+		},
+		{
+			name:  "marked direct reply",
+			tags:  nostr.Tags{{"e", "root", "wss://relay.example.com", "root"}},
+			root:  "root",
+			reply: "root",
+		},
+		{
+			name:  "marked with mention",
+			tags:  nostr.Tags{{"e", "other", "", "mention"}, {"e", "root", "", "root"}, {"e", "parent", "", "reply"}},
+			root:  "root",
+			reply: "parent",
+		},
+		{
+			name:  "positional direct reply",
+			tags:  nostr.Tags{{"e", "root"}},
+			root:  "root",
+			reply: "root",
+		},
+		{
+			name:  "positional reply",
+			tags:  nostr.Tags{{"e", "root"}, {"e", "parent"}},
+			root:  "root",
+			reply: "parent",
+		},
+		{
+			name:  "positional with mention between",
+			tags:  nostr.Tags{{"e", "root"}, {"e", "other"}, {"e", "parent"}},
+			root:  "root",
+			reply: "parent",
+		},
+		{
+			name:  "positional with blank relay",
+			tags:  nostr.Tags{{"e", "root", ""}, {"p", "pk"}, {"e", "parent", ""}},
+			root:  "root",
+			reply: "parent",
+		},
+		{
+			name:  "mention first",
+			tags:  nostr.Tags{{"e", "other", "", "mention"}, {"e", "root"}, {"e", "parent"}},
+			root:  "root",
+			reply: "parent",
+		},
+		{
+			name:  "mixed root marker and unmarked",
+			tags:  nostr.Tags{{"e", "root", "", "root"}, {"e", "other"}},
+			root:  "root",
+			reply: "root",
+		},
+		{
+			name:  "mixed reply marker and unmarked",
+			tags:  nostr.Tags{{"e", "root"}, {"e", "parent", "", "reply"}, {"e", "other"}},
+			root:  "root",
+			reply: "parent",
+		},
+		{
+			name: "only mentions",
+			tags: nostr.Tags{{"e", "other", "", "mention"}},
+		},
+	}
+	for _, tt := range tests {
+		ev := &nostr.Event{Tags: tt.tags}
+		if got := ThreadRootId(ev); got != tt.root {
+			t.Errorf("%s: root %q, want %q", tt.name, got, tt.root)
+		}
+		if got := ReplyToId(ev); got != tt.reply {
+			t.Errorf("%s: reply %q, want %q", tt.name, got, tt.reply)
+		}
+	}
+}
+
+func TestBuildThreadTree(t *testing.T) {
+	root := &nostr.Event{ID: "root", CreatedAt: 1}
+	a := &nostr.Event{ID: "a", CreatedAt: 3, Tags: nostr.Tags{{"e", "root"}}}
+	b := &nostr.Event{ID: "b", CreatedAt: 2, Tags: nostr.Tags{{"e", "root", "", "root"}}}
+	c := &nostr.Event{ID: "c", CreatedAt: 4, Tags: nostr.Tags{{"e", "root"}, {"e", "a"}}}
+	orphan := &nostr.Event{ID: "d", CreatedAt: 5, Tags: nostr.Tags{{"e", "root", "", "root"}, {"e", "gone", "", "reply"}}}
+
+	tree := BuildThreadTree(root, []*nostr.Event{a, b, c, orphan})
+	got := []string{}
+	for _, n := range tree.Replies {
+		got = append(got, n.Event.ID)
+	}
+	if len(got) != 3 || got[0] != "b" || got[1] != "a" || got[2] != "d" {
+		t.Errorf("root replies %v, want [b a d]", got)
+	}
+	if len(tree.Replies[1].Replies) != 1 || tree.Replies[1].Replies[0].Event.ID != "c" {
+		t.Errorf("c not under a")
+	}
+}
@@ -1,4 +1,6 @@
-package main
+// Package domain holds the nostr types and rules that need no relays or
+// storage: profiles, threads, content, lists and NIP-05.
+package domain
 
 type ProfileMetadata struct {
 	Name        string `json:"name,omitempty"`
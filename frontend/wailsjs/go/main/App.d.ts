@@ -1,32 +1,91 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {nostr} from '../models';
-import {main} from '../models';
+import {client} from '../models';
+import {domain} from '../models';
+
+export function AddBookmark(arg1:string,arg2:string,arg3:string,arg4:boolean):Promise<void>;
+
+export function AddToFollowSet(arg1:string,arg2:string,arg3:boolean):Promise<void>;
 
 export function BeginSubscriptions():Promise<void>;
 
+export function CancelSync():Promise<void>;
+
 export function CheckRelays():Promise<void>;
 
+export function CreateFollowSet(arg1:string,arg2:string):Promise<void>;
+
 export function DeleteEvent(arg1:string):Promise<void>;
 
+export function DiffContactListVersions(arg1:string,arg2:string):Promise<any>;
+
 export function DumpEvents():Promise<void>;
 
-export function FollowContact(arg1:Array<string>):Promise<void>;
+export function EncodeEntity(arg1:string,arg2:number,arg3:string):Promise<string>;
+
+export function EncodeEvent(arg1:string):Promise<string>;
+
+export function EncodeNote(arg1:string):Promise<string>;
+
+export function EncodePrivateKey():Promise<string>;
+
+export function EncodeProfile(arg1:string):Promise<string>;
+
+export function ExportEventsToFile(arg1:string,arg2:nostr.Filter,arg3:boolean):Promise<number>;
+
+export function FeedPubkeys(arg1:string):Promise<Array<string>>;
+
+export function FollowContact(arg1:Array<string>):Promise<any>;
+
+export function FollowContactConfirmed(arg1:Array<string>):Promise<any>;
 
 export function GenerateKeys():Promise<any>;
 
+export function GetApiStatus():Promise<any>;
+
+export function GetArticle(arg1:string):Promise<any>;
+
+export function GetArticles(arg1:string,arg2:boolean):Promise<Array<any>>;
+
+export function GetBookmarkLists():Promise<Array<any>>;
+
+export function GetBookmarkedEvents(arg1:string):Promise<Array<any>>;
+
+export function GetBookmarks(arg1:string):Promise<Array<any>>;
+
 export function GetContactList(arg1:string):Promise<Array<string>>;
 
+export function GetContactListVersions():Promise<Array<any>>;
+
 export function GetContactProfile(arg1:string):Promise<any>;
 
-export function GetMetadataEvents(arg1:Array<string>):Promise<void>;
+export function GetEventProvenance(arg1:string):Promise<any>;
+
+export function GetFeeds():Promise<Array<any>>;
+
+export function GetFollowSets():Promise<Array<any>>;
+
+export function GetLocalRelayStatus():Promise<any>;
+
+export function GetMuteList():Promise<Array<any>>;
 
 export function GetMyPubkey():Promise<string>;
 
+export function GetNotifications():Promise<Array<any>>;
+
 export function GetReadableRelays():Promise<Array<any>>;
 
+export function GetRelayRejectCounts():Promise<{[key: string]: number}>;
+
+export function GetRelayStatus():Promise<client.RelayStatus>;
+
 export function GetRelays():Promise<Array<any>>;
 
+export function GetSelectedFeed():Promise<string>;
+
+export function GetSyncProgress():Promise<any>;
+
 export function GetTaggedEvents(arg1:string):Promise<Array<any>>;
 
 export function GetTaggedProfiles(arg1:string):Promise<Array<any>>;
@@ -35,20 +94,48 @@ export function GetTextNotesByEventIds(arg1:Array<string>):Promise<Array<any>>;
 
 export function GetTextNotesForPubkeys(arg1:Array<string>,arg2:string,arg3:boolean):Promise<void>;
 
+export function GetThread(arg1:string):Promise<any>;
+
+export function GetUnreadNotificationCount():Promise<number>;
+
 export function GetWritableRelays():Promise<Array<any>>;
 
+export function ImportEventsFromFile(arg1:string,arg2:Array<string>):Promise<any>;
+
+export function LoadContactList():Promise<Array<string>>;
+
+export function LoadMuteList():Promise<void>;
+
+export function LoadOlder(arg1:string,arg2:nostr.Timestamp,arg3:number):Promise<any>;
+
 export function LoginWithPin(arg1:string):Promise<void>;
 
-export function Nip19Decode(arg1:string):Promise<Array<string>>;
+export function LookupNip05(arg1:string):Promise<any>;
+
+export function MarkAllNotificationsRead():Promise<void>;
+
+export function MarkNotificationsRead(arg1:Array<string>):Promise<void>;
+
+export function MoveToFollowSet(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function Mute(arg1:string,arg2:string,arg3:boolean):Promise<void>;
+
+export function Nip19Decode(arg1:string):Promise<any>;
+
+export function ParseContent(arg1:string,arg2:Array<any>):Promise<Array<any>>;
 
 export function PingTimer():Promise<void>;
 
 export function PkToNpub(arg1:string):Promise<string>;
 
-export function PostEvent(arg1:number,arg2:nostr.Tags,arg3:string):Promise<void>;
+export function PostEvent(arg1:number,arg2:nostr.Tags,arg3:string):Promise<any>;
+
+export function PublishArticle(arg1:domain.Article,arg2:boolean):Promise<any>;
 
 export function PublishContentToSelectedRelays(arg1:number,arg2:string,arg3:Array<any>,arg4:Array<string>):Promise<void>;
 
+export function QueryFeed(arg1:string,arg2:nostr.Timestamp):Promise<Array<any>>;
+
 export function Quit():Promise<void>;
 
 export function RefreshContactProfiles():Promise<void>;
@@ -57,20 +144,44 @@ export function RefreshFeed(arg1:boolean):Promise<void>;
 
 export function RefreshFeedReset():Promise<void>;
 
+export function RemoveBookmark(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function RemoveFromFollowSet(arg1:string,arg2:string):Promise<void>;
+
+export function RestoreContactListVersion(arg1:string):Promise<any>;
+
 export function RestoreContacts():Promise<any>;
 
 export function SaveConfigDark(arg1:boolean):Promise<void>;
 
 export function SaveContacts():Promise<any>;
 
+export function SaveFollowSet(arg1:domain.FollowSet):Promise<void>;
+
 export function SaveNewKeys(arg1:{[key: string]: string}):Promise<void>;
 
-export function SaveProfile(arg1:main.ProfileMetadata):Promise<void>;
+export function SaveProfile(arg1:domain.ProfileMetadata):Promise<void>;
+
+export function SelectFeed(arg1:string):Promise<void>;
+
+export function SendDirectMessage(arg1:string,arg2:string):Promise<any>;
+
+export function SetApiEnabled(arg1:boolean):Promise<any>;
+
+export function SetLocalRelayEnabled(arg1:boolean):Promise<any>;
 
 export function SetLoginWithPrivKey(arg1:Array<string>):Promise<void>;
 
 export function SetRelays(arg1:Array<any>):Promise<void>;
 
-export function SubscribeToFeedForPubkeys(arg1:Array<string>,arg2:boolean):Promise<void>;
+export function SubscribeToFeed(arg1:string,arg2:boolean):Promise<void>;
+
+export function SubscribeToNotifications():Promise<void>;
+
+export function SyncHistory(arg1:string):Promise<void>;
+
+export function UnfollowContact(arg1:string):Promise<any>;
+
+export function Unmute(arg1:string,arg2:string):Promise<void>;
 
-export function UnfollowContact(arg1:string):Promise<void>;
+export function VerifyNip05(arg1:string):Promise<boolean>;
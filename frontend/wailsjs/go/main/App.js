@@ -2,54 +2,170 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddBookmark(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['AddBookmark'](arg1, arg2, arg3, arg4);
+}
+
+export function AddToFollowSet(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddToFollowSet'](arg1, arg2, arg3);
+}
+
 export function BeginSubscriptions() {
   return window['go']['main']['App']['BeginSubscriptions']();
 }
 
+export function CancelSync() {
+  return window['go']['main']['App']['CancelSync']();
+}
+
 export function CheckRelays() {
   return window['go']['main']['App']['CheckRelays']();
 }
 
+export function CreateFollowSet(arg1, arg2) {
+  return window['go']['main']['App']['CreateFollowSet'](arg1, arg2);
+}
+
 export function DeleteEvent(arg1) {
   return window['go']['main']['App']['DeleteEvent'](arg1);
 }
 
+export function DiffContactListVersions(arg1, arg2) {
+  return window['go']['main']['App']['DiffContactListVersions'](arg1, arg2);
+}
+
 export function DumpEvents() {
   return window['go']['main']['App']['DumpEvents']();
 }
 
+export function EncodeEntity(arg1, arg2, arg3) {
+  return window['go']['main']['App']['EncodeEntity'](arg1, arg2, arg3);
+}
+
+export function EncodeEvent(arg1) {
+  return window['go']['main']['App']['EncodeEvent'](arg1);
+}
+
+export function EncodeNote(arg1) {
+  return window['go']['main']['App']['EncodeNote'](arg1);
+}
+
+export function EncodePrivateKey() {
+  return window['go']['main']['App']['EncodePrivateKey']();
+}
+
+export function EncodeProfile(arg1) {
+  return window['go']['main']['App']['EncodeProfile'](arg1);
+}
+
+export function ExportEventsToFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExportEventsToFile'](arg1, arg2, arg3);
+}
+
+export function FeedPubkeys(arg1) {
+  return window['go']['main']['App']['FeedPubkeys'](arg1);
+}
+
 export function FollowContact(arg1) {
   return window['go']['main']['App']['FollowContact'](arg1);
 }
 
+export function FollowContactConfirmed(arg1) {
+  return window['go']['main']['App']['FollowContactConfirmed'](arg1);
+}
+
 export function GenerateKeys() {
   return window['go']['main']['App']['GenerateKeys']();
 }
 
+export function GetApiStatus() {
+  return window['go']['main']['App']['GetApiStatus']();
+}
+
+export function GetArticle(arg1) {
+  return window['go']['main']['App']['GetArticle'](arg1);
+}
+
+export function GetArticles(arg1, arg2) {
+  return window['go']['main']['App']['GetArticles'](arg1, arg2);
+}
+
+export function GetBookmarkLists() {
+  return window['go']['main']['App']['GetBookmarkLists']();
+}
+
+export function GetBookmarkedEvents(arg1) {
+  return window['go']['main']['App']['GetBookmarkedEvents'](arg1);
+}
+
+export function GetBookmarks(arg1) {
+  return window['go']['main']['App']['GetBookmarks'](arg1);
+}
+
 export function GetContactList(arg1) {
   return window['go']['main']['App']['GetContactList'](arg1);
 }
 
+export function GetContactListVersions() {
+  return window['go']['main']['App']['GetContactListVersions']();
+}
+
 export function GetContactProfile(arg1) {
   return window['go']['main']['App']['GetContactProfile'](arg1);
 }
 
-export function GetMetadataEvents(arg1) {
-  return window['go']['main']['App']['GetMetadataEvents'](arg1);
+export function GetEventProvenance(arg1) {
+  return window['go']['main']['App']['GetEventProvenance'](arg1);
+}
+
+export function GetFeeds() {
+  return window['go']['main']['App']['GetFeeds']();
+}
+
+export function GetFollowSets() {
+  return window['go']['main']['App']['GetFollowSets']();
+}
+
+export function GetLocalRelayStatus() {
+  return window['go']['main']['App']['GetLocalRelayStatus']();
+}
+
+export function GetMuteList() {
+  return window['go']['main']['App']['GetMuteList']();
 }
 
 export function GetMyPubkey() {
   return window['go']['main']['App']['GetMyPubkey']();
 }
 
+export function GetNotifications() {
+  return window['go']['main']['App']['GetNotifications']();
+}
+
 export function GetReadableRelays() {
   return window['go']['main']['App']['GetReadableRelays']();
 }
 
+export function GetRelayRejectCounts() {
+  return window['go']['main']['App']['GetRelayRejectCounts']();
+}
+
+export function GetRelayStatus() {
+  return window['go']['main']['App']['GetRelayStatus']();
+}
+
 export function GetRelays() {
   return window['go']['main']['App']['GetRelays']();
 }
 
+export function GetSelectedFeed() {
+  return window['go']['main']['App']['GetSelectedFeed']();
+}
+
+export function GetSyncProgress() {
+  return window['go']['main']['App']['GetSyncProgress']();
+}
+
 export function GetTaggedEvents(arg1) {
   return window['go']['main']['App']['GetTaggedEvents'](arg1);
 }
@@ -66,18 +182,66 @@ export function GetTextNotesForPubkeys(arg1, arg2, arg3) {
   return window['go']['main']['App']['GetTextNotesForPubkeys'](arg1, arg2, arg3);
 }
 
+export function GetThread(arg1) {
+  return window['go']['main']['App']['GetThread'](arg1);
+}
+
+export function GetUnreadNotificationCount() {
+  return window['go']['main']['App']['GetUnreadNotificationCount']();
+}
+
 export function GetWritableRelays() {
   return window['go']['main']['App']['GetWritableRelays']();
 }
 
+export function ImportEventsFromFile(arg1, arg2) {
+  return window['go']['main']['App']['ImportEventsFromFile'](arg1, arg2);
+}
+
+export function LoadContactList() {
+  return window['go']['main']['App']['LoadContactList']();
+}
+
+export function LoadMuteList() {
+  return window['go']['main']['App']['LoadMuteList']();
+}
+
+export function LoadOlder(arg1, arg2, arg3) {
+  return window['go']['main']['App']['LoadOlder'](arg1, arg2, arg3);
+}
+
 export function LoginWithPin(arg1) {
   return window['go']['main']['App']['LoginWithPin'](arg1);
 }
 
+export function LookupNip05(arg1) {
+  return window['go']['main']['App']['LookupNip05'](arg1);
+}
+
+export function MarkAllNotificationsRead() {
+  return window['go']['main']['App']['MarkAllNotificationsRead']();
+}
+
+export function MarkNotificationsRead(arg1) {
+  return window['go']['main']['App']['MarkNotificationsRead'](arg1);
+}
+
+export function MoveToFollowSet(arg1, arg2, arg3) {
+  return window['go']['main']['App']['MoveToFollowSet'](arg1, arg2, arg3);
+}
+
+export function Mute(arg1, arg2, arg3) {
+  return window['go']['main']['App']['Mute'](arg1, arg2, arg3);
+}
+
 export function Nip19Decode(arg1) {
   return window['go']['main']['App']['Nip19Decode'](arg1);
 }
 
+export function ParseContent(arg1, arg2) {
+  return window['go']['main']['App']['ParseContent'](arg1, arg2);
+}
+
 export function PingTimer() {
   return window['go']['main']['App']['PingTimer']();
 }
@@ -90,10 +254,18 @@ export function PostEvent(arg1, arg2, arg3) {
   return window['go']['main']['App']['PostEvent'](arg1, arg2, arg3);
 }
 
+export function PublishArticle(arg1, arg2) {
+  return window['go']['main']['App']['PublishArticle'](arg1, arg2);
+}
+
 export function PublishContentToSelectedRelays(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['PublishContentToSelectedRelays'](arg1, arg2, arg3, arg4);
 }
 
+export function QueryFeed(arg1, arg2) {
+  return window['go']['main']['App']['QueryFeed'](arg1, arg2);
+}
+
 export function Quit() {
   return window['go']['main']['App']['Quit']();
 }
@@ -110,6 +282,18 @@ export function RefreshFeedReset() {
   return window['go']['main']['App']['RefreshFeedReset']();
 }
 
+export function RemoveBookmark(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RemoveBookmark'](arg1, arg2, arg3);
+}
+
+export function RemoveFromFollowSet(arg1, arg2) {
+  return window['go']['main']['App']['RemoveFromFollowSet'](arg1, arg2);
+}
+
+export function RestoreContactListVersion(arg1) {
+  return window['go']['main']['App']['RestoreContactListVersion'](arg1);
+}
+
 export function RestoreContacts() {
   return window['go']['main']['App']['RestoreContacts']();
 }
@@ -122,6 +306,10 @@ export function SaveContacts() {
   return window['go']['main']['App']['SaveContacts']();
 }
 
+export function SaveFollowSet(arg1) {
+  return window['go']['main']['App']['SaveFollowSet'](arg1);
+}
+
 export function SaveNewKeys(arg1) {
   return window['go']['main']['App']['SaveNewKeys'](arg1);
 }
@@ -130,6 +318,22 @@ export function SaveProfile(arg1) {
   return window['go']['main']['App']['SaveProfile'](arg1);
 }
 
+export function SelectFeed(arg1) {
+  return window['go']['main']['App']['SelectFeed'](arg1);
+}
+
+export function SendDirectMessage(arg1, arg2) {
+  return window['go']['main']['App']['SendDirectMessage'](arg1, arg2);
+}
+
+export function SetApiEnabled(arg1) {
+  return window['go']['main']['App']['SetApiEnabled'](arg1);
+}
+
+export function SetLocalRelayEnabled(arg1) {
+  return window['go']['main']['App']['SetLocalRelayEnabled'](arg1);
+}
+
 export function SetLoginWithPrivKey(arg1) {
   return window['go']['main']['App']['SetLoginWithPrivKey'](arg1);
 }
@@ -138,10 +342,26 @@ export function SetRelays(arg1) {
   return window['go']['main']['App']['SetRelays'](arg1);
 }
 
-export function SubscribeToFeedForPubkeys(arg1, arg2) {
-  return window['go']['main']['App']['SubscribeToFeedForPubkeys'](arg1, arg2);
+export function SubscribeToFeed(arg1, arg2) {
+  return window['go']['main']['App']['SubscribeToFeed'](arg1, arg2);
+}
+
+export function SubscribeToNotifications() {
+  return window['go']['main']['App']['SubscribeToNotifications']();
+}
+
+export function SyncHistory(arg1) {
+  return window['go']['main']['App']['SyncHistory'](arg1);
 }
 
 export function UnfollowContact(arg1) {
   return window['go']['main']['App']['UnfollowContact'](arg1);
 }
+
+export function Unmute(arg1, arg2) {
+  return window['go']['main']['App']['Unmute'](arg1, arg2);
+}
+
+export function VerifyNip05(arg1) {
+  return window['go']['main']['App']['VerifyNip05'](arg1);
+}
@@ -1,5 +1,232 @@
-export namespace main {
+export namespace client {
+	
+	export class FeedPage {
+	    events: nostr.Event[];
+	    next: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FeedPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.events = this.convertValues(source["events"], nostr.Event);
+	        this.next = source["next"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImportResult {
+	    read: number;
+	    imported: number;
+	    skipped: number;
+	    invalid: number;
+	    published: {[key: string]: number};
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.read = source["read"];
+	        this.imported = source["imported"];
+	        this.skipped = source["skipped"];
+	        this.invalid = source["invalid"];
+	        this.published = source["published"];
+	    }
+	}
+	export class RelayStatus {
+	    readable: number;
+	    writable: number;
+	    subs: number;
+	    purposes: {[key: string]: number};
+	    dropped: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RelayStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.readable = source["readable"];
+	        this.writable = source["writable"];
+	        this.subs = source["subs"];
+	        this.purposes = source["purposes"];
+	        this.dropped = source["dropped"];
+	    }
+	}
+	export class SyncProgress {
+	    target: string;
+	    state: string;
+	    relay: string;
+	    relays: number;
+	    found: number;
+	    onTarget: number;
+	    missing: number;
+	    published: number;
+	    failed: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.target = source["target"];
+	        this.state = source["state"];
+	        this.relay = source["relay"];
+	        this.relays = source["relays"];
+	        this.found = source["found"];
+	        this.onTarget = source["onTarget"];
+	        this.missing = source["missing"];
+	        this.published = source["published"];
+	        this.failed = source["failed"];
+	        this.error = source["error"];
+	    }
+	}
+
+}
+
+export namespace domain {
+	
+	export class Article {
+	    id: string;
+	    pubkey: string;
+	    kind: number;
+	    identifier: string;
+	    title: string;
+	    summary: string;
+	    image: string;
+	    publishedAt: number;
+	    created_at: number;
+	    hashtags: string[];
+	    content: string;
+	    naddr: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Article(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.pubkey = source["pubkey"];
+	        this.kind = source["kind"];
+	        this.identifier = source["identifier"];
+	        this.title = source["title"];
+	        this.summary = source["summary"];
+	        this.image = source["image"];
+	        this.publishedAt = source["publishedAt"];
+	        this.created_at = source["created_at"];
+	        this.hashtags = source["hashtags"];
+	        this.content = source["content"];
+	        this.naddr = source["naddr"];
+	    }
+	}
+	export class Bookmark {
+	    type: string;
+	    value: string;
+	    private: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Bookmark(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.value = source["value"];
+	        this.private = source["private"];
+	    }
+	}
+	export class BookmarkList {
+	    kind: number;
+	    name: string;
+	    title: string;
+	    items: Bookmark[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BookmarkList(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.name = source["name"];
+	        this.title = source["title"];
+	        this.items = this.convertValues(source["items"], Bookmark);
+	    }
 	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ContactListDiff {
+	    added: string[];
+	    removed: string[];
+	    before: number;
+	    after: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ContactListDiff(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.added = source["added"];
+	        this.removed = source["removed"];
+	        this.before = source["before"];
+	        this.after = source["after"];
+	    }
+	}
+	export class ContactListVersion {
+	    id: string;
+	    created_at: number;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ContactListVersion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.created_at = source["created_at"];
+	        this.count = source["count"];
+	    }
+	}
 	export class ProfileMetadata {
 	    name?: string;
 	    about?: string;
@@ -34,6 +261,8 @@ export namespace main {
 	    meta: ProfileMetadata;
 	    npub: string;
 	    relays: string[];
+	    nip05Verified: boolean;
+	    nip05CheckedAt: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new Profile(source);
@@ -46,6 +275,8 @@ export namespace main {
 	        this.meta = this.convertValues(source["meta"], ProfileMetadata);
 	        this.npub = source["npub"];
 	        this.relays = source["relays"];
+	        this.nip05Verified = source["nip05Verified"];
+	        this.nip05CheckedAt = source["nip05CheckedAt"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -66,23 +297,270 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class Nip19Entity {
+	    type: string;
+	    pubkey: string;
+	    privkey: string;
+	    id: string;
+	    kind: number;
+	    identifier: string;
+	    relays: string[];
 	
-	export class RelayStruct {
-	    url: string;
+	    static createFrom(source: any = {}) {
+	        return new Nip19Entity(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.pubkey = source["pubkey"];
+	        this.privkey = source["privkey"];
+	        this.id = source["id"];
+	        this.kind = source["kind"];
+	        this.identifier = source["identifier"];
+	        this.relays = source["relays"];
+	    }
+	}
+	export class ContentToken {
+	    type: string;
+	    text: string;
+	    value: string;
+	    mediaType?: string;
+	    entity?: Nip19Entity;
+	    profile?: Profile;
+	    event?: nostr.Event;
+	
+	    static createFrom(source: any = {}) {
+	        return new ContentToken(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.text = source["text"];
+	        this.value = source["value"];
+	        this.mediaType = source["mediaType"];
+	        this.entity = this.convertValues(source["entity"], Nip19Entity);
+	        this.profile = this.convertValues(source["profile"], Profile);
+	        this.event = this.convertValues(source["event"], nostr.Event);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Feed {
+	    id: string;
+	    title: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Feed(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.size = source["size"];
+	    }
+	}
+	export class FollowSet {
+	    name: string;
+	    title: string;
+	    pubkeys: string[];
+	    private: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FollowSet(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.title = source["title"];
+	        this.pubkeys = source["pubkeys"];
+	        this.private = source["private"];
+	    }
+	}
+	export class MuteEntry {
+	    type: string;
+	    value: string;
+	    private: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new MuteEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.value = source["value"];
+	        this.private = source["private"];
+	    }
+	}
+	
+	export class Notification {
+	    id: string;
+	    type: string;
+	    from: string;
+	    created_at: number;
 	    read: boolean;
-	    write: boolean;
+	    event?: nostr.Event;
+	
+	    static createFrom(source: any = {}) {
+	        return new Notification(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.type = source["type"];
+	        this.from = source["from"];
+	        this.created_at = source["created_at"];
+	        this.read = source["read"];
+	        this.event = this.convertValues(source["event"], nostr.Event);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class ThreadNode {
+	    event?: nostr.Event;
+	    replies: ThreadNode[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ThreadNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.event = this.convertValues(source["event"], nostr.Event);
+	        this.replies = this.convertValues(source["replies"], ThreadNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Thread {
+	    rootId: string;
+	    focusId: string;
+	    ancestors: nostr.Event[];
+	    root?: ThreadNode;
+	
+	    static createFrom(source: any = {}) {
+	        return new Thread(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rootId = source["rootId"];
+	        this.focusId = source["focusId"];
+	        this.ancestors = this.convertValues(source["ancestors"], nostr.Event);
+	        this.root = this.convertValues(source["root"], ThreadNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace main {
+	
+	export class ApiStatus {
 	    enabled: boolean;
+	    running: boolean;
+	    url: string;
+	    token: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new RelayStruct(source);
+	        return new ApiStatus(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.running = source["running"];
 	        this.url = source["url"];
-	        this.read = source["read"];
-	        this.write = source["write"];
+	        this.token = source["token"];
+	    }
+	}
+	export class LocalRelayStatus {
+	    enabled: boolean;
+	    running: boolean;
+	    url: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalRelayStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.enabled = source["enabled"];
+	        this.running = source["running"];
+	        this.url = source["url"];
 	    }
 	}
 
@@ -114,6 +592,101 @@ export namespace nostr {
 	        this.sig = source["sig"];
 	    }
 	}
+	export class Filter {
+	    ids?: string[];
+	    kinds?: number[];
+	    authors?: string[];
+	    since?: number;
+	    until?: number;
+	    limit?: number;
+	    search?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Filter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ids = source["ids"];
+	        this.kinds = source["kinds"];
+	        this.authors = source["authors"];
+	        this.since = source["since"];
+	        this.until = source["until"];
+	        this.limit = source["limit"];
+	        this.search = source["search"];
+	    }
+	}
+
+}
+
+export namespace relays {
+	
+	export class RelaySighting {
+	    url: string;
+	    firstSeen: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RelaySighting(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.firstSeen = source["firstSeen"];
+	    }
+	}
+	export class EventProvenance {
+	    id: string;
+	    firstSeen: number;
+	    relays: RelaySighting[];
+	
+	    static createFrom(source: any = {}) {
+	        return new EventProvenance(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.firstSeen = source["firstSeen"];
+	        this.relays = this.convertValues(source["relays"], RelaySighting);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Relay {
+	    url: string;
+	    read: boolean;
+	    write: boolean;
+	    enabled: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Relay(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.read = source["read"];
+	        this.write = source["write"];
+	        this.enabled = source["enabled"];
+	    }
+	}
 
 }
 
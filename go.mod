@@ -5,9 +5,11 @@ go 1.18
 require (
 	github.com/arriqaaq/hash v0.1.2
 	github.com/fstanis/screenresolution v0.0.0-20190527020317-869904d15333
+	github.com/gobwas/ws v1.2.0
 	github.com/nbd-wtf/go-nostr v0.18.0
 	github.com/rs/zerolog v1.29.1
 	github.com/wailsapp/wails/v2 v2.4.1
+	golang.org/x/crypto v0.1.0
 )
 
 require (
@@ -21,7 +23,6 @@ require (
 	github.com/go-ole/go-ole v1.2.6 // indirect
 	github.com/gobwas/httphead v0.1.0 // indirect
 	github.com/gobwas/pool v0.2.1 // indirect
-	github.com/gobwas/ws v1.2.0 // indirect
 	github.com/google/uuid v1.1.2 // indirect
 	github.com/jchv/go-winloader v0.0.0-20210711035445-715c2860da7e // indirect
 	github.com/josharian/intern v1.0.0 // indirect
@@ -41,7 +42,6 @@ require (
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.1 // indirect
 	github.com/wailsapp/mimetype v1.4.1 // indirect
-	golang.org/x/crypto v0.1.0 // indirect
 	golang.org/x/exp v0.0.0-20230425010034-47ecfdc1ba53 // indirect
 	golang.org/x/net v0.7.0 // indirect
 	golang.org/x/sys v0.6.0 // indirect
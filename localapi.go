@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"greet/api"
+)
+
+type ApiStatus struct {
+	Enabled bool   `json:"enabled"`
+	Running bool   `json:"running"`
+	Url     string `json:"url"`
+	Token   string `json:"token"`
+}
+
+// startApi starts the local API when it is turned on in the config
+func (a *App) startApi() {
+	if !a.client.Config.ApiEnabled || a.api != nil {
+		return
+	}
+	if a.client.Config.ApiToken == "" {
+		a.client.Config.ApiToken = api.NewToken()
+		a.client.Config.Save()
+	}
+	server := api.New(a.client, a.client.Config.ApiToken)
+	if err := server.Start(a.client.Config.ApiPort); err != nil {
+		log.Error().Msgf("Could not start the local API: %s", err.Error())
+		return
+	}
+	a.api = server
+}
+
+func (a *App) stopApi() {
+	if a.api == nil {
+		return
+	}
+	a.api.Stop()
+	a.api = nil
+}
+
+func (a *App) GetApiStatus() *ApiStatus {
+	return &ApiStatus{
+		Enabled: a.client.Config.ApiEnabled,
+		Running: a.api != nil,
+		Url:     fmt.Sprintf("http://127.0.0.1:%d", a.client.Config.ApiPort),
+		Token:   a.client.Config.ApiToken,
+	}
+}
+
+// SetApiEnabled turns the local API on or off and remembers the choice
+func (a *App) SetApiEnabled(enabled bool) (*ApiStatus, error) {
+	a.client.Config.ApiEnabled = enabled
+	if err := a.client.Config.Save(); err != nil {
+		return nil, err
+	}
+	if enabled {
+		a.startApi()
+	} else {
+		a.stopApi()
+	}
+	return a.GetApiStatus(), nil
+}
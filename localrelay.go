@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"greet/localrelay"
+)
+
+type LocalRelayStatus struct {
+	Enabled bool   `json:"enabled"`
+	Running bool   `json:"running"`
+	Url     string `json:"url"`
+}
+
+// startLocalRelay starts the local relay when it is turned on in the config
+func (a *App) startLocalRelay() {
+	if !a.client.Config.LocalRelayEnabled || a.relay != nil {
+		return
+	}
+	server := localrelay.New(a.client)
+	if err := server.Start(a.client.Config.LocalRelayPort); err != nil {
+		log.Error().Msgf("Could not start the local relay: %s", err.Error())
+		return
+	}
+	a.relay = server
+}
+
+func (a *App) stopLocalRelay() {
+	if a.relay == nil {
+		return
+	}
+	a.relay.Stop()
+	a.relay = nil
+}
+
+func (a *App) GetLocalRelayStatus() *LocalRelayStatus {
+	return &LocalRelayStatus{
+		Enabled: a.client.Config.LocalRelayEnabled,
+		Running: a.relay != nil,
+		Url:     fmt.Sprintf("ws://127.0.0.1:%d", a.client.Config.LocalRelayPort),
+	}
+}
+
+// SetLocalRelayEnabled turns the local relay on or off and remembers the
+// choice
+func (a *App) SetLocalRelayEnabled(enabled bool) (*LocalRelayStatus, error) {
+	a.client.Config.LocalRelayEnabled = enabled
+	if err := a.client.Config.Save(); err != nil {
+		return nil, err
+	}
+	if enabled {
+		a.startLocalRelay()
+	} else {
+		a.stopLocalRelay()
+	}
+	return a.GetLocalRelayStatus(), nil
+}
@@ -0,0 +1,318 @@
+// Package localrelay is a NIP-01 relay on localhost backed by the client's
+// event cache. Other clients on the machine can read what Greet has seen, and
+// events written to it are forwarded to our write relays.
+package localrelay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip11"
+	"github.com/rs/zerolog/log"
+	"greet/client"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits advertised in the NIP-11 document and enforced on REQ
+const (
+	MAX_LIMIT         = 500
+	MAX_SUBSCRIPTIONS = 50
+	MAX_FILTERS       = 10
+)
+
+// Messages queued for a client that is not reading. Live events past this
+// are dropped rather than holding up the cache.
+const SEND_BUFFER = 1024
+
+type Server struct {
+	client   *client.Client
+	http     *http.Server
+	listener int
+
+	mu    sync.Mutex
+	conns map[*conn]bool
+}
+
+// conn is one WebSocket client and its open subscriptions. Messages are
+// written by a goroutine of their own.
+type conn struct {
+	ws   net.Conn
+	out  chan []byte
+	done chan struct{}
+	mu   sync.Mutex
+	subs map[string]nostr.Filters
+}
+
+func New(c *client.Client) *Server {
+	s := &Server{
+		client: c,
+		conns:  map[*conn]bool{},
+	}
+	s.http = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// Start listens on localhost:port and serves in the background
+func (s *Server) Start(port int) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return err
+	}
+	s.listener = s.client.DB.AddListener(s.broadcast)
+	log.Info().Msgf("Local relay listening on ws://%s", ln.Addr())
+	go func() {
+		err := s.http.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Msgf("Local relay stopped: %s", err.Error())
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Stop() {
+	s.client.DB.RemoveListener(s.listener)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.http.Shutdown(ctx)
+
+	// Shutdown leaves hijacked connections alone
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.ws.Close()
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.ToLower(r.Header.Get("Upgrade")) == "websocket" {
+		s.serveWs(w, r)
+		return
+	}
+	if strings.Contains(r.Header.Get("Accept"), "application/nostr+json") {
+		w.Header().Set("Content-Type", "application/nostr+json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(s.info())
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "Greet local relay, connect with a nostr client")
+}
+
+// info is the NIP-11 relay information document
+func (s *Server) info() *nip11.RelayInformationDocument {
+	return &nip11.RelayInformationDocument{
+		Name:          "Greet local relay",
+		Description:   "The events Greet has cached. Events written here are forwarded to Greet's write relays.",
+		PubKey:        s.client.Config.Pubkey,
+		SupportedNIPs: []int{1, 11},
+		Software:      "greet",
+		Limitation: &nip11.RelayLimitationDocument{
+			MaxSubscriptions: MAX_SUBSCRIPTIONS,
+			MaxFilters:       MAX_FILTERS,
+			MaxLimit:         MAX_LIMIT,
+		},
+	}
+}
+
+func (s *Server) serveWs(w http.ResponseWriter, r *http.Request) {
+	wsConn, _, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		log.Debug().Msgf("Local relay WebSocket upgrade failed: %s", err.Error())
+		return
+	}
+	c := &conn{
+		ws:   wsConn,
+		out:  make(chan []byte, SEND_BUFFER),
+		done: make(chan struct{}),
+		subs: map[string]nostr.Filters{},
+	}
+	s.mu.Lock()
+	s.conns[c] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		close(c.done)
+		wsConn.Close()
+	}()
+	go c.writeLoop()
+
+	for {
+		msg, _, err := wsutil.ReadClientData(wsConn)
+		if err != nil {
+			return
+		}
+		s.handleMessage(c, msg)
+	}
+}
+
+func (s *Server) handleMessage(c *conn, msg []byte) {
+	var parts []json.RawMessage
+	if err := json.Unmarshal(msg, &parts); err != nil || len(parts) < 2 {
+		c.send("NOTICE", "Could not parse message")
+		return
+	}
+	var typ string
+	json.Unmarshal(parts[0], &typ)
+
+	switch typ {
+	case "EVENT":
+		var ev nostr.Event
+		if err := json.Unmarshal(parts[1], &ev); err != nil {
+			c.send("NOTICE", "Could not parse event")
+			return
+		}
+		s.handleEvent(c, &ev)
+	case "REQ":
+		var subId string
+		json.Unmarshal(parts[1], &subId)
+		filters := nostr.Filters{}
+		for _, raw := range parts[2:] {
+			var f nostr.Filter
+			if err := json.Unmarshal(raw, &f); err != nil {
+				c.send("CLOSED", subId, "error: could not parse filter")
+				return
+			}
+			filters = append(filters, f)
+		}
+		s.handleReq(c, subId, filters)
+	case "CLOSE":
+		var subId string
+		json.Unmarshal(parts[1], &subId)
+		c.mu.Lock()
+		delete(c.subs, subId)
+		c.mu.Unlock()
+	default:
+		c.send("NOTICE", "Unknown message type "+typ)
+	}
+}
+
+// handleEvent stores and forwards an event. It is accepted once it is in the
+// cache; the write relays are not waited for, so a slow one does not hold up
+// the messages behind it.
+func (s *Server) handleEvent(c *conn, ev *nostr.Event) {
+	if err := s.client.Forward(ev); err != nil {
+		c.send("OK", ev.ID, false, "invalid: "+err.Error())
+		return
+	}
+	c.send("OK", ev.ID, true, "")
+}
+
+// handleReq sends the cached events matching the filters, newest first, then
+// EOSE. The subscription stays open for events added to the cache later.
+func (s *Server) handleReq(c *conn, subId string, filters nostr.Filters) {
+	if subId == "" || len(filters) == 0 || len(filters) > MAX_FILTERS {
+		c.send("CLOSED", subId, "error: need a subscription id and 1 to 10 filters")
+		return
+	}
+	c.mu.Lock()
+	if _, ok := c.subs[subId]; !ok && len(c.subs) >= MAX_SUBSCRIPTIONS {
+		c.mu.Unlock()
+		c.send("CLOSED", subId, "error: too many subscriptions")
+		return
+	}
+	c.subs[subId] = filters
+	c.mu.Unlock()
+
+	sent := map[string]bool{}
+	for _, f := range filters {
+		limit := f.Limit
+		if limit <= 0 || limit > MAX_LIMIT {
+			limit = MAX_LIMIT
+		}
+		events := s.client.DB.QueryEvents(&f)
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].CreatedAt > events[j].CreatedAt
+		})
+		for _, ev := range events {
+			if limit == 0 {
+				break
+			}
+			if sent[ev.ID] || s.client.DB.IsDeleted(ev) {
+				continue
+			}
+			sent[ev.ID] = true
+			limit--
+			c.send("EVENT", subId, ev)
+		}
+	}
+	c.send("EOSE", subId)
+}
+
+// broadcast passes an event newly added to the cache to the subscriptions
+// it matches
+func (s *Server) broadcast(ev *nostr.Event) {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.mu.Lock()
+		matched := []string{}
+		for subId, filters := range c.subs {
+			if filters.Match(ev) {
+				matched = append(matched, subId)
+			}
+		}
+		c.mu.Unlock()
+		for _, subId := range matched {
+			if !c.trySend("EVENT", subId, ev) {
+				log.Debug().Msgf("Local relay client is behind, dropped %s", ev.ID)
+			}
+		}
+	}
+}
+
+func (c *conn) writeLoop() {
+	for {
+		select {
+		case j := <-c.out:
+			c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := wsutil.WriteServerText(c.ws, j); err != nil {
+				c.ws.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// send queues a message, waiting for room
+func (c *conn) send(msg ...interface{}) {
+	j, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	select {
+	case c.out <- j:
+	case <-c.done:
+	}
+}
+
+// trySend queues a message unless the queue is full
+func (c *conn) trySend(msg ...interface{}) bool {
+	j, err := json.Marshal(msg)
+	if err != nil {
+		return false
+	}
+	select {
+	case c.out <- j:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,176 @@
+package localrelay
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/nbd-wtf/go-nostr"
+	"greet/client"
+	"greet/config"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServer serves a local relay over a headless client with a fresh key
+// and no relays, and connects to it
+func testServer(t *testing.T) (*Server, net.Conn) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cfg := config.NewConfig()
+	cfg.PrivKeyHex = nostr.GeneratePrivateKey()
+	pk, err := nostr.GetPublicKey(cfg.PrivKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Pubkey = pk
+	s := New(client.New(cfg, client.NewRecorder()))
+	s.listener = s.client.DB.AddListener(s.broadcast)
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	conn, _, _, err := ws.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s, conn
+}
+
+func signed(t *testing.T, sk string, content string, at nostr.Timestamp) *nostr.Event {
+	t.Helper()
+	pk, _ := nostr.GetPublicKey(sk)
+	ev := &nostr.Event{PubKey: pk, CreatedAt: at, Kind: nostr.KindTextNote, Tags: nostr.Tags{}, Content: content}
+	if err := ev.Sign(sk); err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func write(t *testing.T, conn net.Conn, msg ...interface{}) {
+	t.Helper()
+	j, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wsutil.WriteClientText(conn, j); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// read returns the type of the next message and the rest of it
+func read(t *testing.T, conn net.Conn) (string, []json.RawMessage) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg, err := wsutil.ReadServerText(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(msg, &parts); err != nil || len(parts) == 0 {
+		t.Fatalf("Could not parse %s", msg)
+	}
+	var typ string
+	json.Unmarshal(parts[0], &typ)
+	return typ, parts[1:]
+}
+
+func str(raw json.RawMessage) string {
+	var s string
+	json.Unmarshal(raw, &s)
+	return s
+}
+
+func TestEventIsAccepted(t *testing.T) {
+	s, conn := testServer(t)
+	ev := signed(t, nostr.GeneratePrivateKey(), "Hello", nostr.Now())
+	write(t, conn, "EVENT", ev)
+
+	typ, parts := read(t, conn)
+	var ok bool
+	if typ == "OK" {
+		json.Unmarshal(parts[1], &ok)
+	}
+	if typ != "OK" || str(parts[0]) != ev.ID || !ok {
+		t.Fatalf("Got %s %s, want OK true for %s", typ, parts, ev.ID)
+	}
+	if !s.client.DB.HasEvent(ev.ID) {
+		t.Error("Accepted event is not cached")
+	}
+}
+
+func TestEventWithBadSignatureIsRejected(t *testing.T) {
+	s, conn := testServer(t)
+	ev := signed(t, nostr.GeneratePrivateKey(), "Hello", nostr.Now())
+	ev.Content = "Forged"
+	write(t, conn, "EVENT", ev)
+
+	typ, parts := read(t, conn)
+	var ok bool
+	if typ == "OK" {
+		json.Unmarshal(parts[1], &ok)
+	}
+	if typ != "OK" || ok || !strings.HasPrefix(str(parts[2]), "invalid:") {
+		t.Fatalf("Got %s %s, want OK false and invalid", typ, parts)
+	}
+	if s.client.DB.HasEvent(ev.ID) {
+		t.Error("Forged event was cached")
+	}
+}
+
+func TestReqSendsNewestUpToLimit(t *testing.T) {
+	s, conn := testServer(t)
+	sk := nostr.GeneratePrivateKey()
+	now := nostr.Now()
+	evs := []*nostr.Event{}
+	for i := 0; i < 3; i++ {
+		ev := signed(t, sk, "Note", now-nostr.Timestamp(30-i))
+		s.client.DB.AddEvent(ev.ID, ev)
+		evs = append(evs, ev)
+	}
+
+	write(t, conn, "REQ", "notes", nostr.Filter{Kinds: []int{nostr.KindTextNote}, Limit: 2})
+	for _, want := range []*nostr.Event{evs[2], evs[1]} {
+		typ, parts := read(t, conn)
+		var got nostr.Event
+		if typ == "EVENT" {
+			json.Unmarshal(parts[1], &got)
+		}
+		if typ != "EVENT" || str(parts[0]) != "notes" || got.ID != want.ID {
+			t.Fatalf("Got %s %s, want the event %s", typ, parts, want.ID)
+		}
+	}
+	if typ, parts := read(t, conn); typ != "EOSE" || str(parts[0]) != "notes" {
+		t.Errorf("Got %s %s after the limit, want EOSE", typ, parts)
+	}
+}
+
+func TestCloseEndsSubscription(t *testing.T) {
+	s, conn := testServer(t)
+	notes := nostr.Filter{Kinds: []int{nostr.KindTextNote}}
+	for _, subId := range []string{"closed", "open"} {
+		write(t, conn, "REQ", subId, notes)
+		if typ, _ := read(t, conn); typ != "EOSE" {
+			t.Fatalf("Got %s for an empty cache, want EOSE", typ)
+		}
+	}
+	write(t, conn, "CLOSE", "closed")
+	// The reply to a later REQ shows the CLOSE has been handled
+	write(t, conn, "REQ", "sync", nostr.Filter{Kinds: []int{nostr.KindReaction}})
+	if typ, _ := read(t, conn); typ != "EOSE" {
+		t.Fatalf("Got %s, want EOSE", typ)
+	}
+
+	ev := signed(t, nostr.GeneratePrivateKey(), "Live", nostr.Now())
+	s.client.DB.AddEvent(ev.ID, ev)
+	if typ, parts := read(t, conn); typ != "EVENT" || str(parts[0]) != "open" {
+		t.Fatalf("Got %s %s, want the event on the open subscription", typ, parts)
+	}
+	write(t, conn, "REQ", "sync", nostr.Filter{Kinds: []int{nostr.KindReaction}})
+	if typ, parts := read(t, conn); typ != "EOSE" {
+		t.Errorf("Got %s %s, want nothing on the closed subscription", typ, parts)
+	}
+}
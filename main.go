@@ -10,14 +10,13 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/logger"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"os"
 	"strings"
 )
 
 //go:embed all:frontend/dist
 var assets embed.FS
 
-var app *App
-
 func main() {
 
 	var logging string
@@ -25,7 +24,7 @@ func main() {
 	flag.Parse()
 
 	// Create an instance of the app structure
-	app = NewApp()
+	app := NewApp()
 
 	switch strings.ToUpper(logging) {
 	case "DEBUG":
@@ -36,6 +35,11 @@ func main() {
 		app.logging = zerolog.InfoLevel
 	}
 
+	// A command runs headless, without the desktop window
+	if flag.NArg() > 0 {
+		os.Exit(runCli(app, flag.Args()))
+	}
+
 	res := screenresolution.GetPrimary()
 	width := int(float64(res.Width) * 0.7)
 	height := int(float64(res.Height) * 0.9)
@@ -0,0 +1,341 @@
+package relays
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"math"
+	"sort"
+)
+
+// The initiator side of negentropy, the range-based set reconciliation used
+// by NIP-77. Both sides sort their events by (created_at, id), and ranges are
+// compared by fingerprint, split while they differ and listed by id once
+// small. See https://github.com/hoytech/negentropy for the protocol.
+
+const NEG_PROTOCOL_VERSION = 0x61
+
+const (
+	negIdSize          = 32
+	negFingerprintSize = 16
+	negBuckets         = 16
+	negMaxTimestamp    = math.MaxUint64
+)
+
+// Range modes
+const (
+	negSkip        = 0
+	negFingerprint = 1
+	negIdList      = 2
+)
+
+var errNegMessage = errors.New("malformed negentropy message")
+
+type negItem struct {
+	timestamp uint64
+	id        []byte
+}
+
+// negBound is a range boundary, the prefix of an id being enough to tell two
+// items with the same timestamp apart
+type negBound struct {
+	timestamp uint64
+	prefix    []byte
+}
+
+type negentropy struct {
+	items []negItem
+
+	// Timestamps are delta encoded within a message
+	lastIn  uint64
+	lastOut uint64
+}
+
+func newNegentropy(events []*nostr.Event) (*negentropy, error) {
+	n := &negentropy{items: make([]negItem, 0, len(events))}
+	for _, ev := range events {
+		id, err := hex.DecodeString(ev.ID)
+		if err != nil || len(id) != negIdSize {
+			return nil, fmt.Errorf("bad event id %q", ev.ID)
+		}
+		n.items = append(n.items, negItem{uint64(ev.CreatedAt), id})
+	}
+	sort.Slice(n.items, func(i, j int) bool {
+		a, b := n.items[i], n.items[j]
+		if a.timestamp != b.timestamp {
+			return a.timestamp < b.timestamp
+		}
+		return bytes.Compare(a.id, b.id) < 0
+	})
+	return n, nil
+}
+
+// initiate returns the first message, fingerprints of the whole set
+func (n *negentropy) initiate() []byte {
+	n.lastOut = 0
+	out := []byte{NEG_PROTOCOL_VERSION}
+	return n.splitRange(out, 0, len(n.items), negBound{timestamp: negMaxTimestamp})
+}
+
+// reconcile answers a message from the relay. The ids found only on our
+// side go to have, those only on theirs to need. A nil reply means the sets
+// are reconciled.
+func (n *negentropy) reconcile(msg []byte, have, need *[]string) ([]byte, error) {
+	n.lastIn, n.lastOut = 0, 0
+	r := &negReader{buf: msg}
+	version, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if version != NEG_PROTOCOL_VERSION {
+		return nil, fmt.Errorf("unsupported negentropy protocol version 0x%x", version)
+	}
+
+	out := []byte{NEG_PROTOCOL_VERSION}
+	prevIndex := 0
+	prevBound := negBound{}
+	skip := false
+	for len(r.buf) > 0 {
+		bound, err := n.readBound(r)
+		if err != nil {
+			return nil, err
+		}
+		mode, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		lower := prevIndex
+		upper := n.lowerBound(prevIndex, bound)
+
+		switch mode {
+		case negSkip:
+			skip = true
+		case negFingerprint:
+			theirs, err := r.bytes(negFingerprintSize)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(theirs, n.fingerprint(lower, upper)) {
+				skip = true
+				break
+			}
+			if skip {
+				skip = false
+				out = n.appendBound(out, prevBound)
+				out = appendVarint(out, negSkip)
+			}
+			out = n.splitRange(out, lower, upper, bound)
+		case negIdList:
+			count, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			theirs := map[string]bool{}
+			for i := uint64(0); i < count; i++ {
+				id, err := r.bytes(negIdSize)
+				if err != nil {
+					return nil, err
+				}
+				theirs[string(id)] = true
+			}
+			for _, item := range n.items[lower:upper] {
+				if theirs[string(item.id)] {
+					delete(theirs, string(item.id))
+				} else {
+					*have = append(*have, hex.EncodeToString(item.id))
+				}
+			}
+			for id := range theirs {
+				*need = append(*need, hex.EncodeToString([]byte(id)))
+			}
+			skip = true
+		default:
+			return nil, errNegMessage
+		}
+		prevIndex = upper
+		prevBound = bound
+	}
+
+	if len(out) == 1 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// splitRange describes items[lower:upper], listing the ids of a small range
+// and fingerprinting buckets of a large one
+func (n *negentropy) splitRange(out []byte, lower, upper int, upperBound negBound) []byte {
+	count := upper - lower
+	if count < negBuckets*2 {
+		out = n.appendBound(out, upperBound)
+		out = appendVarint(out, negIdList)
+		out = appendVarint(out, uint64(count))
+		for _, item := range n.items[lower:upper] {
+			out = append(out, item.id...)
+		}
+		return out
+	}
+
+	perBucket, extra := count/negBuckets, count%negBuckets
+	curr := lower
+	for i := 0; i < negBuckets; i++ {
+		size := perBucket
+		if i < extra {
+			size++
+		}
+		fingerprint := n.fingerprint(curr, curr+size)
+		curr += size
+
+		bound := upperBound
+		if curr != upper {
+			bound = minimalBound(n.items[curr-1], n.items[curr])
+		}
+		out = n.appendBound(out, bound)
+		out = appendVarint(out, negFingerprint)
+		out = append(out, fingerprint...)
+	}
+	return out
+}
+
+// fingerprint hashes the sum of the ids, as 256-bit little endian numbers,
+// and their count
+func (n *negentropy) fingerprint(lower, upper int) []byte {
+	var sum [negIdSize]byte
+	for _, item := range n.items[lower:upper] {
+		carry := 0
+		for i := 0; i < negIdSize; i++ {
+			v := int(sum[i]) + int(item.id[i]) + carry
+			sum[i] = byte(v)
+			carry = v >> 8
+		}
+	}
+	h := sha256.Sum256(appendVarint(sum[:], uint64(upper-lower)))
+	return h[:negFingerprintSize]
+}
+
+// lowerBound finds the first item from start that is not below the bound
+func (n *negentropy) lowerBound(start int, bound negBound) int {
+	padded := make([]byte, negIdSize)
+	copy(padded, bound.prefix)
+	return start + sort.Search(len(n.items)-start, func(i int) bool {
+		item := n.items[start+i]
+		if item.timestamp != bound.timestamp {
+			return item.timestamp > bound.timestamp
+		}
+		return bytes.Compare(item.id, padded) >= 0
+	})
+}
+
+// minimalBound is the shortest bound that falls between two adjacent items
+func minimalBound(prev, curr negItem) negBound {
+	if prev.timestamp != curr.timestamp {
+		return negBound{timestamp: curr.timestamp}
+	}
+	shared := 0
+	for shared < negIdSize && prev.id[shared] == curr.id[shared] {
+		shared++
+	}
+	return negBound{timestamp: curr.timestamp, prefix: curr.id[:shared+1]}
+}
+
+func (n *negentropy) appendBound(out []byte, b negBound) []byte {
+	// 0 stands for infinity, anything else is one more than the delta
+	if b.timestamp == negMaxTimestamp {
+		out = appendVarint(out, 0)
+		n.lastOut = negMaxTimestamp
+	} else {
+		out = appendVarint(out, b.timestamp-n.lastOut+1)
+		n.lastOut = b.timestamp
+	}
+	out = appendVarint(out, uint64(len(b.prefix)))
+	return append(out, b.prefix...)
+}
+
+func (n *negentropy) readBound(r *negReader) (negBound, error) {
+	ts, err := r.varint()
+	if err != nil {
+		return negBound{}, err
+	}
+	if ts == 0 {
+		ts = negMaxTimestamp
+	} else if ts-1 >= negMaxTimestamp-n.lastIn {
+		// Nothing follows infinity, and no delta reaches it
+		return negBound{}, errNegMessage
+	} else {
+		ts = ts - 1 + n.lastIn
+	}
+	n.lastIn = ts
+	size, err := r.varint()
+	if err != nil {
+		return negBound{}, err
+	}
+	if size > negIdSize {
+		return negBound{}, errNegMessage
+	}
+	prefix, err := r.bytes(int(size))
+	if err != nil {
+		return negBound{}, err
+	}
+	return negBound{timestamp: ts, prefix: prefix}, nil
+}
+
+// appendVarint writes n big endian in 7 bit groups, the high bit set on all
+// but the last byte
+func appendVarint(out []byte, n uint64) []byte {
+	var groups []byte
+	for {
+		groups = append(groups, byte(n&0x7f))
+		n >>= 7
+		if n == 0 {
+			break
+		}
+	}
+	for i := len(groups) - 1; i >= 0; i-- {
+		b := groups[i]
+		if i > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+type negReader struct {
+	buf []byte
+}
+
+func (r *negReader) byte() (byte, error) {
+	if len(r.buf) == 0 {
+		return 0, errNegMessage
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b, nil
+}
+
+func (r *negReader) bytes(size int) ([]byte, error) {
+	if len(r.buf) < size {
+		return nil, errNegMessage
+	}
+	b := r.buf[:size]
+	r.buf = r.buf[size:]
+	return b, nil
+}
+
+func (r *negReader) varint() (uint64, error) {
+	var n uint64
+	for i := 0; i < 10; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<7 | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+	return 0, errNegMessage
+}
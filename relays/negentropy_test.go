@@ -0,0 +1,408 @@
+package relays
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/nbd-wtf/go-nostr"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// negEvents makes events with the ids of the reference vectors, one second
+// apart from 1700000000 or all in that second
+func negEvents(indexes []int, sameSecond bool) []*nostr.Event {
+	evs := []*nostr.Event{}
+	for _, i := range indexes {
+		ts := nostr.Timestamp(1700000000)
+		if !sameSecond {
+			ts += nostr.Timestamp(i)
+		}
+		evs = append(evs, &nostr.Event{ID: fmt.Sprintf("%064x", i*7919+1), CreatedAt: ts})
+	}
+	return evs
+}
+
+func negRange(from, to int) []int {
+	r := []int{}
+	for i := from; i < to; i++ {
+		r = append(r, i)
+	}
+	return r
+}
+
+func testNegentropy(t *testing.T, indexes []int, sameSecond bool) *negentropy {
+	t.Helper()
+	n, err := newNegentropy(negEvents(indexes, sameSecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// Vectors from the reference implementation, hoytech/negentropy
+func TestNegentropyVarint(t *testing.T) {
+	vectors := map[uint64]string{
+		0:          "00",
+		1:          "01",
+		127:        "7f",
+		128:        "8100",
+		300:        "822c",
+		16383:      "ff7f",
+		16384:      "818000",
+		1700000000: "86aacfe200",
+	}
+	for n, want := range vectors {
+		enc := appendVarint(nil, n)
+		if got := hex.EncodeToString(enc); got != want {
+			t.Errorf("Varint %d is %s, want %s", n, got, want)
+		}
+		r := &negReader{buf: enc}
+		if back, err := r.varint(); err != nil || back != n || len(r.buf) != 0 {
+			t.Errorf("Varint %s read as %d, %v", want, back, err)
+		}
+	}
+	if _, err := (&negReader{buf: []byte{0x81}}).varint(); err != errNegMessage {
+		t.Errorf("Truncated varint read with %v, want errNegMessage", err)
+	}
+}
+
+func TestNegentropyFingerprint(t *testing.T) {
+	n := testNegentropy(t, negRange(0, 40), false)
+	vectors := map[int]string{
+		0:  "7f9c9e31ac8256ca2f258583df262dbc",
+		3:  "452ee607f1e15f410a359aa6a59dd502",
+		40: "e8abb20c062e53e0ee180fe2744c01b4",
+	}
+	for upper, want := range vectors {
+		if got := hex.EncodeToString(n.fingerprint(0, upper)); got != want {
+			t.Errorf("Fingerprint of %d ids is %s, want %s", upper, got, want)
+		}
+	}
+}
+
+func TestNegentropyInitiate(t *testing.T) {
+	vectors := []struct {
+		count int
+		want  string
+	}{
+		{3, "61000002030000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000001ef0" +
+			"0000000000000000000000000000000000000000000000000000000000003ddf"},
+		{40, "6186aacfe2040001452ee607f1e15f410a359aa6a59dd5020400010376206d176b7b0e4bd20544a6591f33" +
+			"04000150805b021f3fe059d57154214d1ae9f2040001898791c8858fa9be54cf4566e6e11e3b" +
+			"0400017e9648088131cb0e3ef7722d209358730400014ae3c9d5a366bb3db11e3d124827bc45" +
+			"0400011f35e8f1df9fe3d2b00b3e093fd1bdeb04000172eefe940135bf83bfc8b04dcce3871d" +
+			"030001183f3ad24f7256bd2becaf651e5cf91f030001f629ddab1618959d4f7fae87b861bbcd" +
+			"030001d2c35dbb59fc3827a22bf9197a0cb5c9030001f058e1530444c3d56e0acb653efe98e5" +
+			"030001347843d46478933d38d878a92d0c4d38030001bb8e66c998aadfc8bf5b64bec564b125" +
+			"030001467d64e28ae6a71c138c54eb3ccbf43b000001682c57d2c0f3d1d1e3ee18251d7c7550"},
+	}
+	for _, v := range vectors {
+		n := testNegentropy(t, negRange(0, v.count), false)
+		if got := hex.EncodeToString(n.initiate()); got != v.want {
+			t.Errorf("First message for %d ids is\n%s, want\n%s", v.count, got, v.want)
+		}
+	}
+}
+
+func TestNegentropyBound(t *testing.T) {
+	id := func(s string) []byte {
+		b, _ := hex.DecodeString(strings.Repeat("00", 29) + s)
+		return b
+	}
+	// Items in the same second are told apart by the id up to the first
+	// byte that differs
+	b := minimalBound(negItem{5, id("0a0b0c")}, negItem{5, id("0a0d0c")})
+	if b.timestamp != 5 || hex.EncodeToString(b.prefix) != strings.Repeat("00", 29)+"0a0d" {
+		t.Errorf("Bound %d %x, want 5 and the id up to 0d", b.timestamp, b.prefix)
+	}
+	if b := minimalBound(negItem{5, id("0a")}, negItem{7, id("01")}); b.timestamp != 7 || len(b.prefix) != 0 {
+		t.Errorf("Bound %d %x, want 7 and no prefix", b.timestamp, b.prefix)
+	}
+
+	// Timestamps are deltas plus one, 0 is infinity
+	n := &negentropy{}
+	out := n.appendBound(nil, negBound{timestamp: 1700000000})
+	out = n.appendBound(out, negBound{timestamp: 1700000010, prefix: []byte{0xab}})
+	out = n.appendBound(out, negBound{timestamp: negMaxTimestamp})
+	if got := hex.EncodeToString(out); got != "86aacfe201000b01ab0000" {
+		t.Errorf("Bounds encoded as %s", got)
+	}
+	r := &negReader{buf: out}
+	for _, want := range []uint64{1700000000, 1700000010, negMaxTimestamp} {
+		if got, err := n.readBound(r); err != nil || got.timestamp != want {
+			t.Errorf("Bound read as %d, %v, want %d", got.timestamp, err, want)
+		}
+	}
+
+	// Anything after infinity, or past it, is malformed
+	for _, deltas := range [][]uint64{{0, 2}, {5, math.MaxUint64 - 2}} {
+		msg := []byte{}
+		for _, d := range deltas {
+			msg = append(appendVarint(msg, d), 0)
+		}
+		n.lastIn = 0
+		r := &negReader{buf: msg}
+		var err error
+		for err == nil && len(r.buf) > 0 {
+			_, err = n.readBound(r)
+		}
+		if err != errNegMessage {
+			t.Errorf("Bounds %x read with %v, want errNegMessage", msg, err)
+		}
+	}
+}
+
+// respond is the relay's side of the protocol, built on the same primitives
+// as hoytech/negentropy's responder
+func (n *negentropy) respond(msg []byte) ([]byte, error) {
+	n.lastIn, n.lastOut = 0, 0
+	r := &negReader{buf: msg}
+	if version, err := r.byte(); err != nil || version != NEG_PROTOCOL_VERSION {
+		return nil, errNegMessage
+	}
+	out := []byte{NEG_PROTOCOL_VERSION}
+	prevIndex := 0
+	prevBound := negBound{}
+	skip := false
+	doSkip := func() {
+		if skip {
+			skip = false
+			out = n.appendBound(out, prevBound)
+			out = appendVarint(out, negSkip)
+		}
+	}
+	for len(r.buf) > 0 {
+		bound, err := n.readBound(r)
+		if err != nil {
+			return nil, err
+		}
+		mode, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		lower := prevIndex
+		upper := n.lowerBound(prevIndex, bound)
+
+		switch mode {
+		case negSkip:
+			skip = true
+		case negFingerprint:
+			theirs, err := r.bytes(negFingerprintSize)
+			if err != nil {
+				return nil, err
+			}
+			if string(theirs) == string(n.fingerprint(lower, upper)) {
+				skip = true
+				break
+			}
+			doSkip()
+			out = n.splitRange(out, lower, upper, bound)
+		case negIdList:
+			count, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := r.bytes(int(count) * negIdSize); err != nil {
+				return nil, err
+			}
+			doSkip()
+			out = n.appendBound(out, bound)
+			out = appendVarint(out, negIdList)
+			out = appendVarint(out, uint64(upper-lower))
+			for _, item := range n.items[lower:upper] {
+				out = append(out, item.id...)
+			}
+		default:
+			return nil, errNegMessage
+		}
+		prevIndex = upper
+		prevBound = bound
+	}
+	return out, nil
+}
+
+// negRoundTrip reconciles ours with theirs and returns the sorted have and
+// need, failing if it takes more rounds than a correct run could
+func negRoundTrip(t *testing.T, ours, theirs *negentropy) ([]string, []string) {
+	t.Helper()
+	have, need := []string{}, []string{}
+	msg := ours.initiate()
+	for round := 0; msg != nil; round++ {
+		if round > 10 {
+			t.Fatal("Reconciliation did not finish")
+		}
+		reply, err := theirs.respond(msg)
+		if err != nil {
+			t.Fatalf("Relay could not read round %d: %v", round, err)
+		}
+		msg, err = ours.reconcile(reply, &have, &need)
+		if err != nil {
+			t.Fatalf("Could not read reply %d: %v", round, err)
+		}
+	}
+	sort.Strings(have)
+	sort.Strings(need)
+	return have, need
+}
+
+func negIds(indexes []int) []string {
+	ids := []string{}
+	for _, ev := range negEvents(indexes, false) {
+		ids = append(ids, ev.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestNegentropyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		ours       []int
+		theirs     []int
+		sameSecond bool
+		have, need []int
+	}{
+		{"both empty", nil, nil, false, nil, nil},
+		{"we have none", nil, negRange(0, 5), false, nil, negRange(0, 5)},
+		{"they have none", negRange(0, 5), nil, false, negRange(0, 5), nil},
+		{"same", negRange(0, 20), negRange(0, 20), false, nil, nil},
+		{"overlapping", negRange(0, 20), negRange(10, 30), false, negRange(0, 10), negRange(20, 30)},
+		{"disjoint", negRange(0, 10), negRange(10, 20), false, negRange(0, 10), negRange(10, 20)},
+		{"large", negRange(0, 1000), append(negRange(0, 500), negRange(501, 1100)...), false, []int{500}, negRange(1000, 1100)},
+		{"large same second", negRange(0, 300), negRange(5, 310), true, negRange(0, 5), negRange(300, 310)},
+		{"large against empty", negRange(0, 200), nil, false, negRange(0, 200), nil},
+		{"empty against large", nil, negRange(0, 200), false, nil, negRange(0, 200)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, need := negRoundTrip(t, testNegentropy(t, tt.ours, tt.sameSecond), testNegentropy(t, tt.theirs, tt.sameSecond))
+			if want := negIds(tt.have); strings.Join(have, ",") != strings.Join(want, ",") {
+				t.Errorf("Have %d ids, want %d", len(have), len(want))
+			}
+			if want := negIds(tt.need); strings.Join(need, ",") != strings.Join(want, ",") {
+				t.Errorf("Need %d ids, want %d", len(need), len(want))
+			}
+		})
+	}
+}
+
+// negRelay serves a websocket that hands each message from the client to
+// answer, and returns its url
+func negRelay(t *testing.T, answer func(conn net.Conn, parts []json.RawMessage)) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			msg, _, err := wsutil.ReadClientData(conn)
+			if err != nil {
+				return
+			}
+			var parts []json.RawMessage
+			if json.Unmarshal(msg, &parts) == nil && len(parts) > 0 {
+				answer(conn, parts)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func negSend(conn net.Conn, msg ...interface{}) {
+	b, _ := json.Marshal(msg)
+	wsutil.WriteServerText(conn, b)
+}
+
+func TestNegentropySession(t *testing.T) {
+	theirs := testNegentropy(t, negRange(0, 60), false)
+	url := negRelay(t, func(conn net.Conn, parts []json.RawMessage) {
+		var typ, subId, body string
+		json.Unmarshal(parts[0], &typ)
+		json.Unmarshal(parts[1], &subId)
+		switch typ {
+		case "NEG-OPEN":
+			// Chatter about other things is not an answer
+			negSend(conn, "NOTICE", "slow down")
+			json.Unmarshal(parts[3], &body)
+		case "NEG-MSG":
+			json.Unmarshal(parts[2], &body)
+		default:
+			return
+		}
+		msg, _ := hex.DecodeString(body)
+		reply, err := theirs.respond(msg)
+		if err != nil {
+			negSend(conn, "NEG-ERR", subId, "error: "+err.Error())
+			return
+		}
+		negSend(conn, "NEG-MSG", subId, hex.EncodeToString(reply))
+	})
+
+	have, need, err := negentropySession(context.Background(), url, &nostr.Filter{}, negEvents(negRange(10, 70), false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(have)
+	sort.Strings(need)
+	if strings.Join(have, ",") != strings.Join(negIds(negRange(60, 70)), ",") {
+		t.Errorf("Have %d ids, want the 10 from 60", len(have))
+	}
+	if strings.Join(need, ",") != strings.Join(negIds(negRange(0, 10)), ",") {
+		t.Errorf("Need %d ids, want the 10 below 10", len(need))
+	}
+}
+
+func TestNegentropySessionRefused(t *testing.T) {
+	tests := []struct {
+		name        string
+		answer      func(conn net.Conn, subId string)
+		unsupported bool
+	}{
+		{"notice", func(conn net.Conn, subId string) {
+			negSend(conn, "NOTICE", "ERROR: unknown cmd NEG-OPEN")
+		}, true},
+		{"neg-err", func(conn net.Conn, subId string) {
+			negSend(conn, "NEG-ERR", "neg-other", "blocked: not yours")
+			negSend(conn, "NEG-ERR", subId, "blocked: negentropy disabled")
+		}, true},
+		{"closed", func(conn net.Conn, subId string) {
+			negSend(conn, "CLOSED", subId, "error: unknown subscription type")
+		}, true},
+		{"rate limited", func(conn net.Conn, subId string) {
+			negSend(conn, "CLOSED", subId, "rate-limited: slow down")
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := negRelay(t, func(conn net.Conn, parts []json.RawMessage) {
+				var typ, subId string
+				json.Unmarshal(parts[0], &typ)
+				json.Unmarshal(parts[1], &subId)
+				if typ == "NEG-OPEN" {
+					tt.answer(conn, subId)
+				}
+			})
+			_, _, err := negentropySession(context.Background(), url, &nostr.Filter{}, nil)
+			if err == nil {
+				t.Fatal("Session succeeded")
+			}
+			if errors.Is(err, errNegUnsupported) != tt.unsupported {
+				t.Errorf("Error %q, want unsupported %v", err.Error(), tt.unsupported)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+// Package relays manages connections to relays and checks what they send.
+package relays
+
+import (
+	"context"
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+const MAX_RELAY_HINTS = 3
+
+type Pool struct {
+	pool       []*Relay
+	poolMu     sync.RWMutex
+	rootCtx    context.Context
+	verified   *verifiedCache
+	provenance *provenanceStore
+	rejects    map[string]int64
+	rejectMu   sync.Mutex
+
+	// Relays that did not answer NIP-77
+	noNegentropy map[string]bool
+	negMu        sync.Mutex
+
+	subs *subManager
+}
+
+func NewPool() *Pool {
+	return &Pool{
+		pool:       []*Relay{},
+		rootCtx:    context.Background(),
+		verified:   newVerifiedCache(),
+		provenance: newProvenanceStore(),
+		rejects:    make(map[string]int64),
+
+		noNegentropy: make(map[string]bool),
+		subs:         newSubManager(),
+	}
+}
+
+func (p *Pool) Add(relay *Relay) error {
+	if relay.Enabled {
+		log.Debug().Msgf("Adding relay %s to pool", relay.Url)
+		err := relay.Connect(p.rootCtx)
+		if err != nil {
+			return err
+		}
+		p.poolMu.Lock()
+		p.pool = append(p.pool, relay)
+		p.poolMu.Unlock()
+		p.resubscribeAfter(SUB_SETTLE)
+		go func() {
+			if err := relay.FetchInfo(p.rootCtx); err != nil {
+				log.Debug().Msgf("No relay information from %s: %s", relay.Url, err.Error())
+				return
+			}
+			// Replan within the relay's limits
+			p.resubscribeAfter(SUB_SETTLE)
+		}()
+	}
+
+	return nil
+}
+
+// QuerySync sends each verified event matching the filter to c once, however
+// many relays return it, and closes c when all relays are done
+func (p *Pool) QuerySync(f *nostr.Filter, c chan *nostr.Event) {
+	dedup := newEventDedup()
+	wg := sync.WaitGroup{}
+	for _, relay := range p.Relays() {
+		if relay.Enabled && relay.Read {
+			wg.Add(1)
+			go func(r *Relay) {
+				defer wg.Done()
+				result, err := r.conn.QuerySync(p.rootCtx, *f)
+				if err != nil {
+					log.Error().Msgf("QuerySync error from %s: %s", r.Url, err.Error())
+
+					// Try a reconnect, but disable relay if it fails
+					time.Sleep(time.Second * 2)
+					r.Connect(p.rootCtx)
+					result, err = r.conn.QuerySync(p.rootCtx, *f)
+					if err != nil {
+						log.Error().Msgf("QuerySync (retry) error from %s: %s: disabled", r.Url, err.Error())
+						r.Enabled = false
+						return
+					}
+				}
+				for i := 0; i < len(result); i++ {
+					ev := result[i]
+					if !p.accept(r.Url, ev, dedup) {
+						continue
+					}
+					ev.SetExtra("relay", r.Url)
+					c <- ev
+				}
+			}(relay)
+		}
+	}
+	wg.Wait()
+	close(c)
+}
+
+// QueryAll runs QuerySync and collects the results
+func (p *Pool) QueryAll(f *nostr.Filter) []*nostr.Event {
+	events := []*nostr.Event{}
+	ch := make(chan *nostr.Event)
+	go p.QuerySync(f, ch)
+	for ev := range ch {
+		events = append(events, ev)
+	}
+	return events
+}
+
+// QueryRelay runs a query against one relay in the pool, for paging through
+// a relay on its own. Cancelling ctx stops it.
+func (p *Pool) QueryRelay(ctx context.Context, url string, f *nostr.Filter) ([]*nostr.Event, error) {
+	r := p.GetRelayByUrl(url)
+	if r == nil || !r.Connected() {
+		return nil, errors.New("Not connected to " + url)
+	}
+	result, err := r.conn.QuerySync(ctx, *f)
+	if err != nil {
+		return nil, err
+	}
+	events := []*nostr.Event{}
+	for _, ev := range result {
+		if p.accept(r.Url, ev, nil) {
+			ev.SetExtra("relay", r.Url)
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+// QueryPage asks every read relay for up to f.Limit events, capped at each
+// relay's max_limit, and merges them. A relay that returns a full page may
+// have more before its oldest event, so the highest such timestamp comes back
+// as the floor above which the result is complete, 0 if it all is.
+func (p *Pool) QueryPage(ctx context.Context, f *nostr.Filter) ([]*nostr.Event, nostr.Timestamp) {
+	dedup := newEventDedup()
+	events := []*nostr.Event{}
+	var floor nostr.Timestamp
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, relay := range p.Relays() {
+		if !relay.Enabled || !relay.Read || !relay.Connected() {
+			continue
+		}
+		wg.Add(1)
+		go func(r *Relay) {
+			defer wg.Done()
+			filter := *f
+			if maxLimit := r.MaxLimit(); maxLimit > 0 && (filter.Limit == 0 || filter.Limit > maxLimit) {
+				filter.Limit = maxLimit
+			}
+			result, err := p.QueryRelay(ctx, r.Url, &filter)
+			if err != nil {
+				log.Error().Msgf("Page query error from %s: %s", r.Url, err.Error())
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if filter.Limit > 0 && len(result) >= filter.Limit {
+				oldest := result[0].CreatedAt
+				for _, ev := range result {
+					if ev.CreatedAt < oldest {
+						oldest = ev.CreatedAt
+					}
+				}
+				if oldest > floor {
+					floor = oldest
+				}
+			}
+			for _, ev := range result {
+				if dedup.first(ev.ID) {
+					events = append(events, ev)
+				}
+			}
+		}(relay)
+	}
+	wg.Wait()
+	return events, floor
+}
+
+// QueryWithHints is QueryAll plus relays we are not configured for, such as
+// hints from NIP-05 or NIP-19. Those are only connected for the query.
+func (p *Pool) QueryWithHints(f *nostr.Filter, hints []string) []*nostr.Event {
+	events := p.QueryAll(f)
+	dedup := newEventDedup()
+	for _, ev := range events {
+		dedup.first(ev.ID)
+	}
+
+	for i, url := range hints {
+		if i >= MAX_RELAY_HINTS {
+			break
+		}
+		if p.GetRelayByUrl(url) != nil || p.GetRelayByUrl(nostr.NormalizeURL(url)) != nil {
+			continue
+		}
+		log.Debug().Msgf("Querying hinted relay %s", url)
+		ctx, cancel := context.WithTimeout(p.rootCtx, time.Second*7)
+		conn, err := nostr.RelayConnect(ctx, url)
+		if err == nil {
+			conn.AssumeValid = true
+		}
+		if err != nil {
+			cancel()
+			log.Error().Msgf("Could not connect to hinted relay %s: %s", url, err.Error())
+			continue
+		}
+		result, err := conn.QuerySync(ctx, *f)
+		conn.Close()
+		cancel()
+		if err != nil {
+			log.Error().Msgf("QuerySync error from hinted relay %s: %s", url, err.Error())
+			continue
+		}
+		for _, ev := range result {
+			if !p.accept(url, ev, dedup) {
+				continue
+			}
+			ev.SetExtra("relay", url)
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func (p *Pool) AddAll(relays []*Relay) {
+	for _, r := range relays {
+		err := p.Add(r)
+		if err != nil {
+			log.Error().Msgf("Could not add %s to pool: %s", r.Url, err.Error())
+		}
+	}
+}
+
+func (p *Pool) RemoveAll() {
+	p.DisconnectAll()
+	p.poolMu.Lock()
+	p.pool = []*Relay{}
+	p.poolMu.Unlock()
+}
+
+func (p *Pool) DisconnectAll() {
+	p.closeReqs()
+	for _, r := range p.Relays() {
+		if r.Enabled {
+			log.Debug().Msgf("Closing connection to relay %s", r.Url)
+			err := r.conn.Close()
+			if err != nil {
+				log.Err(err)
+			}
+		}
+	}
+}
+
+// Relays returns a copy of the relays in the pool, safe to range over while
+// relays are added or removed
+func (p *Pool) Relays() []*Relay {
+	p.poolMu.RLock()
+	defer p.poolMu.RUnlock()
+	return append([]*Relay{}, p.pool...)
+}
+
+func (p *Pool) GetRelayByUrl(url string) *Relay {
+	for _, r := range p.Relays() {
+		if r.Url == url {
+			return r
+		}
+	}
+	return nil
+}
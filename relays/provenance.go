@@ -0,0 +1,102 @@
+package relays
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Events tracked before the oldest half is dropped
+const PROVENANCE_SIZE = 50000
+
+type RelaySighting struct {
+	Url       string `json:"url"`
+	FirstSeen int64  `json:"firstSeen"`
+}
+
+type EventProvenance struct {
+	Id        string           `json:"id"`
+	FirstSeen int64            `json:"firstSeen"`
+	Relays    []*RelaySighting `json:"relays"`
+}
+
+// provenanceStore records every relay each event was seen on, with the
+// time it first came from that relay. Bounded like verifiedCache, with
+// entries still in use carried over to the new generation.
+type provenanceStore struct {
+	mu       sync.Mutex
+	current  map[string]*EventProvenance
+	previous map[string]*EventProvenance
+}
+
+func newProvenanceStore() *provenanceStore {
+	return &provenanceStore{
+		current:  make(map[string]*EventProvenance),
+		previous: make(map[string]*EventProvenance),
+	}
+}
+
+// get returns the entry for id, callers hold the lock
+func (s *provenanceStore) get(id string) *EventProvenance {
+	if p, ok := s.current[id]; ok {
+		return p
+	}
+	if p, ok := s.previous[id]; ok {
+		s.current[id] = p
+		delete(s.previous, id)
+		return p
+	}
+	return nil
+}
+
+// seen records that url delivered the event, returning false if it already had
+func (s *provenanceStore) seen(id string, url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Unix()
+
+	p := s.get(id)
+	if p == nil {
+		if len(s.current) >= PROVENANCE_SIZE/2 {
+			s.previous = s.current
+			s.current = make(map[string]*EventProvenance)
+		}
+		p = &EventProvenance{Id: id, FirstSeen: now, Relays: []*RelaySighting{}}
+		s.current[id] = p
+	}
+	for _, r := range p.Relays {
+		if r.Url == url {
+			return false
+		}
+	}
+	p.Relays = append(p.Relays, &RelaySighting{Url: url, FirstSeen: now})
+	return true
+}
+
+// lookup returns a copy of the provenance of an event, relays in the order
+// they delivered it
+func (s *provenanceStore) lookup(id string) *EventProvenance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.get(id)
+	if p == nil {
+		return nil
+	}
+	copied := EventProvenance{Id: p.Id, FirstSeen: p.FirstSeen, Relays: []*RelaySighting{}}
+	for _, r := range p.Relays {
+		copied.Relays = append(copied.Relays, &RelaySighting{Url: r.Url, FirstSeen: r.FirstSeen})
+	}
+	sort.SliceStable(copied.Relays, func(i, j int) bool {
+		return copied.Relays[i].FirstSeen < copied.Relays[j].FirstSeen
+	})
+	return &copied
+}
+
+func (p *Pool) GetProvenance(id string) *EventProvenance {
+	return p.provenance.lookup(id)
+}
+
+// RecordSeen credits a relay with an event, such as one we published to it
+func (p *Pool) RecordSeen(id string, url string) {
+	p.provenance.seen(id, url)
+}
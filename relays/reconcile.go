@@ -0,0 +1,335 @@
+package relays
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a relay gets to answer a negentropy message. A relay that does
+// not know NIP-77 often stays silent.
+const NEG_TIMEOUT = 10 * time.Second
+
+// Events asked for per REQ when paging through a relay, and ids asked for
+// per REQ when fetching the events a reconciliation found
+const (
+	PAGE_SIZE     = 500
+	FETCH_ID_SIZE = 100
+)
+
+// errNegUnsupported is remembered per relay, so the REQ fallback is used
+// straight away next time. A relay that is only slow to answer falls back
+// for the one call.
+var errNegUnsupported = errors.New("relay does not support negentropy")
+
+// Reconciliation is the difference between our events and a relay's for a
+// filter
+type Reconciliation struct {
+	Url        string `json:"url"`
+	Negentropy bool   `json:"negentropy"`
+	// Ids we have and the relay lacks
+	Have []string `json:"have"`
+	// Ids the relay has and we lack, fetched and verified into Events
+	Need   []string       `json:"need"`
+	Events []*nostr.Event `json:"-"`
+}
+
+// Reconcile finds which events matching the filter only we or only the relay
+// have, fetching the relay's. NIP-77 negentropy transfers just the ids that
+// differ; relays without it are paged through with REQ instead. The filter's
+// limit is ignored, bound it with since.
+func (p *Pool) Reconcile(ctx context.Context, url string, f *nostr.Filter, local []*nostr.Event) (*Reconciliation, error) {
+	r := p.GetRelayByUrl(url)
+	if r == nil || !r.Connected() {
+		return nil, errors.New("Not connected to " + url)
+	}
+	filter := *f
+	filter.Limit = 0
+	matching := []*nostr.Event{}
+	for _, ev := range local {
+		if filter.Matches(ev) {
+			matching = append(matching, ev)
+		}
+	}
+
+	if p.negentropySupported(url) {
+		have, need, err := negentropySession(ctx, url, &filter, matching)
+		if err == nil {
+			rec := &Reconciliation{Url: url, Negentropy: true, Have: have, Need: need}
+			rec.Events, err = p.fetchIds(ctx, url, need)
+			return rec, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, errNegUnsupported) {
+			p.negMu.Lock()
+			p.noNegentropy[url] = true
+			p.negMu.Unlock()
+		}
+		log.Debug().Msgf("Negentropy with %s failed, using REQ: %s", url, err.Error())
+	}
+	return p.reconcileByQuery(ctx, url, &filter, matching)
+}
+
+// Pull reconciles with every read relay at once and returns the events that
+// local lacks, each once
+func (p *Pool) Pull(ctx context.Context, f *nostr.Filter, local []*nostr.Event) []*nostr.Event {
+	dedup := newEventDedup()
+	events := []*nostr.Event{}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, relay := range p.Relays() {
+		if !relay.Enabled || !relay.Read || !relay.Connected() {
+			continue
+		}
+		wg.Add(1)
+		go func(r *Relay) {
+			defer wg.Done()
+			rec, err := p.Reconcile(ctx, r.Url, f, local)
+			if err != nil {
+				log.Error().Msgf("Could not reconcile with %s: %s", r.Url, err.Error())
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, ev := range rec.Events {
+				if dedup.first(ev.ID) {
+					events = append(events, ev)
+				}
+			}
+		}(relay)
+	}
+	wg.Wait()
+	return events
+}
+
+func (p *Pool) negentropySupported(url string) bool {
+	p.negMu.Lock()
+	defer p.negMu.Unlock()
+	return !p.noNegentropy[url]
+}
+
+// negentropySession runs NIP-77 on a connection of its own, as the pool's
+// connections do not pass NEG-* messages on
+func negentropySession(ctx context.Context, url string, f *nostr.Filter, local []*nostr.Event) ([]string, []string, error) {
+	neg, err := newNegentropy(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	conn, err := nostr.NewConnection(ctx, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	// Closing the connection is the only way to interrupt a read
+	msgs := make(chan []byte)
+	go func() {
+		defer close(msgs)
+		for {
+			msg, err := conn.ReadMessage(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	subId := "neg-" + hex.EncodeToString(b)
+	if err := conn.WriteJSON([]interface{}{"NEG-OPEN", subId, f, hex.EncodeToString(neg.initiate())}); err != nil {
+		return nil, nil, err
+	}
+
+	have, need := []string{}, []string{}
+	answered := false
+	timer := time.NewTimer(NEG_TIMEOUT)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-timer.C:
+			return nil, nil, errors.New("no negentropy answer")
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil, nil, errors.New("connection closed")
+			}
+			var parts []json.RawMessage
+			if err := json.Unmarshal(msg, &parts); err != nil || len(parts) < 2 {
+				continue
+			}
+			var typ, first string
+			json.Unmarshal(parts[0], &typ)
+			json.Unmarshal(parts[1], &first)
+
+			switch typ {
+			case "NOTICE":
+				// A NOTICE names no subscription, so only one about the
+				// NEG-OPEN before any NEG-MSG is taken as the answer
+				if answered || !negRefusalNotice(first) {
+					log.Debug().Msgf("Notice from %s during negentropy: %s", url, first)
+					continue
+				}
+				return nil, nil, fmt.Errorf("%w: %s", errNegUnsupported, first)
+			case "NEG-ERR", "CLOSED":
+				if first != subId {
+					continue
+				}
+				var reason string
+				if len(parts) > 2 {
+					json.Unmarshal(parts[2], &reason)
+				}
+				if strings.HasPrefix(reason, "auth-required:") || strings.HasPrefix(reason, "rate-limited:") {
+					return nil, nil, errors.New("negentropy refused: " + reason)
+				}
+				return nil, nil, fmt.Errorf("%w: %s", errNegUnsupported, reason)
+			case "NEG-MSG":
+				if first != subId || len(parts) < 3 {
+					continue
+				}
+				answered = true
+				var body string
+				json.Unmarshal(parts[2], &body)
+				query, err := hex.DecodeString(body)
+				if err != nil {
+					return nil, nil, err
+				}
+				reply, err := neg.reconcile(query, &have, &need)
+				if err != nil {
+					return nil, nil, err
+				}
+				if reply == nil {
+					conn.WriteJSON([]interface{}{"NEG-CLOSE", subId})
+					log.Debug().Msgf("Negentropy with %s: %d only here, %d only there", url, len(have), len(need))
+					return have, need, nil
+				}
+				if err := conn.WriteJSON([]interface{}{"NEG-MSG", subId, hex.EncodeToString(reply)}); err != nil {
+					return nil, nil, err
+				}
+				timer.Reset(NEG_TIMEOUT)
+			}
+		}
+	}
+}
+
+// negRefusalNotice tells a NOTICE about an unknown or unsupported NEG-OPEN
+// from one about anything else
+func negRefusalNotice(text string) bool {
+	text = strings.ToLower(text)
+	for _, word := range []string{"neg-", "negentropy", "unknown", "unsupported", "not supported"} {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchIds gets the events with the given ids from one relay
+func (p *Pool) fetchIds(ctx context.Context, url string, ids []string) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for i := 0; i < len(ids); i += FETCH_ID_SIZE {
+		end := i + FETCH_ID_SIZE
+		if end > len(ids) {
+			end = len(ids)
+		}
+		result, err := p.QueryRelay(ctx, url, &nostr.Filter{IDs: ids[i:end], Limit: end - i})
+		if err != nil {
+			return events, err
+		}
+		events = append(events, result...)
+	}
+	return events, nil
+}
+
+// reconcileByQuery is the fallback for relays without negentropy: all of the
+// relay's matching events are fetched and compared with ours
+func (p *Pool) reconcileByQuery(ctx context.Context, url string, f *nostr.Filter, local []*nostr.Event) (*Reconciliation, error) {
+	theirs := map[string]bool{}
+	rec := &Reconciliation{Url: url, Have: []string{}, Need: []string{}}
+	ours := map[string]bool{}
+	for _, ev := range local {
+		ours[ev.ID] = true
+	}
+	err := p.PageRelay(ctx, url, f, func(events []*nostr.Event) {
+		for _, ev := range events {
+			theirs[ev.ID] = true
+			if !ours[ev.ID] {
+				rec.Need = append(rec.Need, ev.ID)
+				rec.Events = append(rec.Events, ev)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for id := range ours {
+		if !theirs[id] {
+			rec.Have = append(rec.Have, id)
+		}
+	}
+	return rec, nil
+}
+
+// PageRelay pages back through the events matching the filter on one relay,
+// newest first, passing each page on. The cursor is the oldest timestamp
+// seen; a page with nothing new steps it back a second, so a page boundary
+// inside one second does not loop forever.
+func (p *Pool) PageRelay(ctx context.Context, url string, f *nostr.Filter, page func(events []*nostr.Event)) error {
+	seen := map[string]bool{}
+	filter := *f
+	filter.Limit = PAGE_SIZE
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		events, err := p.QueryRelay(ctx, url, &filter)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		oldest := events[0].CreatedAt
+		fresh := []*nostr.Event{}
+		for _, ev := range events {
+			if ev.CreatedAt < oldest {
+				oldest = ev.CreatedAt
+			}
+			if !seen[ev.ID] {
+				seen[ev.ID] = true
+				fresh = append(fresh, ev)
+			}
+		}
+		if len(fresh) > 0 {
+			page(fresh)
+		} else {
+			oldest--
+		}
+		if oldest <= 0 || (f.Since != nil && oldest < *f.Since) {
+			return nil
+		}
+		filter.Until = &oldest
+	}
+}
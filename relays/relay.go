@@ -1,28 +1,32 @@
-package main
+package relays
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/rs/zerolog/log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
-type RelayStruct struct {
+type Relay struct {
 	Url       string `json:"url"`
 	Read      bool   `json:"read"`
 	Write     bool   `json:"write"`
 	Enabled   bool   `json:"enabled"`
 	conn      *nostr.Relay
-	subs      []*nostr.Subscription
 	relayMeta *RelayMetadata
+	metaMu    sync.Mutex
 }
 
-func NewRelay() *RelayStruct {
-	return &RelayStruct{
+func NewRelay() *Relay {
+	return &Relay{
 		Url:     "",
 		Read:    false,
 		Write:   false,
 		Enabled: false,
-		subs:    []*nostr.Subscription{},
 		relayMeta: &RelayMetadata{
 			Name:                   "",
 			Description:            "",
@@ -89,20 +93,82 @@ type RelayMetadata struct {
 	} `json:"fees"`
 }
 
-func (r *RelayStruct) Connect(ctx context.Context) error {
+func (r *Relay) Connect(ctx context.Context) error {
 	conn, err := nostr.RelayConnect(ctx, r.Url)
 	if err != nil {
 		return err
 	}
 	log.Debug().Msgf("Successful connection to %s", r.Url)
+	// Signatures are checked once per event by the pool, not per relay copy
+	conn.AssumeValid = true
 	r.conn = conn
 	return nil
 }
 
-func (r *RelayStruct) RemoveSub(id string) {
-	for i, s := range r.subs {
-		if s.GetID() == id {
-			r.subs = append(r.subs[:i], r.subs[i+1:]...)
-		}
+// FetchInfo reads the relay's NIP-11 document, for its limits. A relay
+// without one keeps the empty defaults, meaning no known limits.
+func (r *Relay) FetchInfo(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 7*time.Second)
+	defer cancel()
+	url := "http" + strings.TrimPrefix(r.Url, "ws")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/nostr+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	meta := &RelayMetadata{}
+	if err := json.NewDecoder(resp.Body).Decode(meta); err != nil {
+		return err
+	}
+	r.metaMu.Lock()
+	r.relayMeta = meta
+	r.metaMu.Unlock()
+	log.Debug().Msgf("Relay %s limits: %+v", r.Url, meta.Limitation)
+	return nil
+}
+
+// MaxLimit is the most events the relay returns for one filter, 0 if unknown
+func (r *Relay) MaxLimit() int {
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	if r.relayMeta == nil {
+		return 0
+	}
+	return r.relayMeta.Limitation.MaxLimit
+}
+
+// MaxSubscriptions is how many subscriptions the relay allows open at once,
+// 0 if unknown
+func (r *Relay) MaxSubscriptions() int {
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	if r.relayMeta == nil {
+		return 0
 	}
+	return r.relayMeta.Limitation.MaxSubscriptions
+}
+
+// MaxFilters is how many filters the relay takes in one REQ, 0 if unknown
+func (r *Relay) MaxFilters() int {
+	r.metaMu.Lock()
+	defer r.metaMu.Unlock()
+	if r.relayMeta == nil {
+		return 0
+	}
+	return r.relayMeta.Limitation.MaxFilters
+}
+
+// Connected reports whether the relay connection is up
+func (r *Relay) Connected() bool {
+	return r.conn != nil && r.conn.ConnectionError == nil
+}
+
+func (r *Relay) Publish(ctx context.Context, ev nostr.Event) (nostr.Status, error) {
+	return r.conn.Publish(ctx, ev)
 }
@@ -0,0 +1,468 @@
+package relays
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Subscription purposes
+const (
+	SUB_FEED          = "feed"
+	SUB_DELETIONS     = "deletions"
+	SUB_NOTIFICATIONS = "notifications"
+)
+
+// Limits for relays whose NIP-11 document gives none. QUERY_RESERVE
+// subscriptions are left free on every relay for queries.
+const (
+	DEFAULT_MAX_SUBSCRIPTIONS = 20
+	DEFAULT_MAX_FILTERS       = 10
+	QUERY_RESERVE             = 4
+	MAX_MERGED_AUTHORS        = 500
+)
+
+// Changes settle this long before REQs are reopened, so subscribing to a
+// feed chunk by chunk costs one round. A REQ the relay ended is retried
+// after RESUBSCRIBE_DELAY.
+const (
+	SUB_SETTLE        = 100 * time.Millisecond
+	RESUBSCRIBE_DELAY = 5 * time.Second
+)
+
+type SubHandle uint64
+
+// EventHandler gets each event of a subscription once across relays. Live
+// is set for events that arrive after a relay's stored events.
+type EventHandler func(ev *nostr.Event, live bool)
+
+// logicalSub is a subscription as the client asked for it. It is served by
+// REQs on every read relay, shared with other subscriptions.
+type logicalSub struct {
+	handle  SubHandle
+	purpose string
+	filter  nostr.Filter
+	handler EventHandler
+	dedup   *eventDedup
+
+	mu     sync.Mutex
+	closed bool
+	live   map[string]bool
+}
+
+// relayReq is one REQ open on a relay and the subscriptions it carries
+type relayReq struct {
+	key    string
+	subs   []*logicalSub
+	cancel context.CancelFunc
+	ended  bool
+}
+
+type subManager struct {
+	mu      sync.Mutex
+	next    SubHandle
+	logical map[SubHandle]*logicalSub
+	reqs    map[string][]*relayReq
+	pending bool
+
+	// Subscriptions left out on a relay for want of room
+	dropped map[string][]SubHandle
+}
+
+func newSubManager() *subManager {
+	return &subManager{
+		logical: map[SubHandle]*logicalSub{},
+		reqs:    map[string][]*relayReq{},
+		dropped: map[string][]SubHandle{},
+	}
+}
+
+// Subscribe adds a subscription on every read relay, now and as relays are
+// added, until it is torn down with Unsubscribe
+func (p *Pool) Subscribe(purpose string, f nostr.Filter, handler EventHandler) SubHandle {
+	m := p.subs
+	m.mu.Lock()
+	m.next++
+	ls := &logicalSub{
+		handle:  m.next,
+		purpose: purpose,
+		filter:  f,
+		handler: handler,
+		dedup:   newEventDedup(),
+		live:    map[string]bool{},
+	}
+	m.logical[ls.handle] = ls
+	m.mu.Unlock()
+	log.Debug().Msgf("Subscription %d for %s", ls.handle, purpose)
+	p.resubscribeAfter(SUB_SETTLE)
+	return ls.handle
+}
+
+// Unsubscribe tears down subscriptions. Their handlers get nothing more,
+// and REQs left serving nothing are closed.
+func (p *Pool) Unsubscribe(handles ...SubHandle) {
+	m := p.subs
+	m.mu.Lock()
+	for _, h := range handles {
+		if ls, ok := m.logical[h]; ok {
+			ls.mu.Lock()
+			ls.closed = true
+			ls.mu.Unlock()
+			delete(m.logical, h)
+		}
+	}
+	m.mu.Unlock()
+	p.resubscribeAfter(SUB_SETTLE)
+}
+
+// SubCount is the number of REQs open on a relay
+func (p *Pool) SubCount(url string) int {
+	p.subs.mu.Lock()
+	defer p.subs.mu.Unlock()
+	return len(p.subs.reqs[url])
+}
+
+// SubsDropped is the number of subscriptions a relay is not serving as its
+// limits left no room for them
+func (p *Pool) SubsDropped(url string) int {
+	p.subs.mu.Lock()
+	defer p.subs.mu.Unlock()
+	return len(p.subs.dropped[url])
+}
+
+// SubPurposes counts the subscriptions by purpose
+func (p *Pool) SubPurposes() map[string]int {
+	p.subs.mu.Lock()
+	defer p.subs.mu.Unlock()
+	purposes := map[string]int{}
+	for _, ls := range p.subs.logical {
+		purposes[ls.purpose]++
+	}
+	return purposes
+}
+
+func (p *Pool) resubscribeAfter(delay time.Duration) {
+	m := p.subs
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending {
+		return
+	}
+	m.pending = true
+	time.AfterFunc(delay, p.resubscribe)
+}
+
+// resubscribe plans the REQs for every read relay and opens or closes what
+// differs from those already open. A REQ whose filters are unchanged is kept
+// unless it gained subscriptions, which need the stored events from a new one.
+func (p *Pool) resubscribe() {
+	type opening struct {
+		relay   *Relay
+		req     *relayReq
+		filters nostr.Filters
+		ctx     context.Context
+	}
+	toOpen := []opening{}
+	toClose := []*relayReq{}
+	relays := p.Relays()
+
+	m := p.subs
+	m.mu.Lock()
+	m.pending = false
+	logical := make([]*logicalSub, 0, len(m.logical))
+	for _, ls := range m.logical {
+		logical = append(logical, ls)
+	}
+	sort.Slice(logical, func(i, j int) bool {
+		return logical[i].handle < logical[j].handle
+	})
+
+	serving := map[string]bool{}
+	m.dropped = map[string][]SubHandle{}
+	for _, r := range relays {
+		if !r.Enabled || !r.Read || !r.Connected() {
+			continue
+		}
+		serving[r.Url] = true
+		open := map[string][]*relayReq{}
+		for _, req := range m.reqs[r.Url] {
+			open[req.key] = append(open[req.key], req)
+		}
+		kept := []*relayReq{}
+		plan, dropped := planReqs(r, logical)
+		for _, planned := range plan {
+			if reqs := open[planned.key]; len(reqs) > 0 && !gainsSubs(reqs[0].subs, planned.subs) {
+				open[planned.key] = reqs[1:]
+				reqs[0].subs = planned.subs
+				kept = append(kept, reqs[0])
+				continue
+			}
+			// Stored events from the new REQ are not live for any of its
+			// subscriptions until its EOSE
+			for _, ls := range planned.subs {
+				ls.mu.Lock()
+				ls.live[r.Url] = false
+				ls.mu.Unlock()
+			}
+			ctx, cancel := context.WithCancel(p.rootCtx)
+			req := &relayReq{key: planned.key, subs: planned.subs, cancel: cancel}
+			kept = append(kept, req)
+			toOpen = append(toOpen, opening{r, req, planned.filters, ctx})
+		}
+		for _, reqs := range open {
+			toClose = append(toClose, reqs...)
+		}
+		m.reqs[r.Url] = kept
+		if len(dropped) > 0 {
+			handles := make([]SubHandle, len(dropped))
+			for i, ls := range dropped {
+				handles[i] = ls.handle
+			}
+			m.dropped[r.Url] = handles
+		}
+	}
+	for url, reqs := range m.reqs {
+		if !serving[url] {
+			toClose = append(toClose, reqs...)
+			delete(m.reqs, url)
+		}
+	}
+	for _, req := range toClose {
+		req.ended = true
+	}
+	m.mu.Unlock()
+
+	for _, req := range toClose {
+		req.cancel()
+	}
+	for _, o := range toOpen {
+		go p.runReq(o.ctx, o.relay, o.req, o.filters)
+	}
+}
+
+// gainsSubs tells whether planned has subscriptions that open lacks
+func gainsSubs(open, planned []*logicalSub) bool {
+	have := map[*logicalSub]bool{}
+	for _, ls := range open {
+		have[ls] = true
+	}
+	for _, ls := range planned {
+		if !have[ls] {
+			return true
+		}
+	}
+	return false
+}
+
+// closeReqs closes every open REQ, keeping the subscriptions to reopen on
+// relays added later
+func (p *Pool) closeReqs() {
+	m := p.subs
+	m.mu.Lock()
+	reqs := m.reqs
+	m.reqs = map[string][]*relayReq{}
+	for _, open := range reqs {
+		for _, req := range open {
+			req.ended = true
+		}
+	}
+	m.mu.Unlock()
+	for _, open := range reqs {
+		for _, req := range open {
+			req.cancel()
+		}
+	}
+}
+
+func (p *Pool) runReq(ctx context.Context, r *Relay, req *relayReq, filters nostr.Filters) {
+	sub, err := r.conn.Subscribe(ctx, filters)
+	if err != nil {
+		log.Error().Msgf("Could not subscribe to %s: %s", r.Url, err.Error())
+		p.reqEnded(r.Url, req)
+		return
+	}
+	log.Debug().Msgf("Subscribed to relay %s, ID %s, %d filters for %d subscriptions",
+		r.Url, sub.GetID(), len(filters), len(p.subs.reqSubs(req)))
+
+	for {
+		select {
+		case ev := <-sub.Events:
+			if ev == nil {
+				p.reqEnded(r.Url, req)
+				return
+			}
+			log.Trace().Msgf("Got event from %s %s", r.Url, ev.ID)
+			if !p.accept(r.Url, ev, nil) {
+				continue
+			}
+			ev.SetExtra("relay", r.Url)
+			for _, ls := range p.subs.reqSubs(req) {
+				ls.deliver(r.Url, ev)
+			}
+		case <-sub.EndOfStoredEvents:
+			log.Debug().Msgf("Got EOSE from %s", r.Url)
+			for _, ls := range p.subs.reqSubs(req) {
+				ls.mu.Lock()
+				ls.live[r.Url] = true
+				ls.mu.Unlock()
+			}
+		case <-sub.Context.Done():
+			log.Debug().Msgf("Subscription %s to relay %s completed", sub.GetID(), r.Url)
+			p.reqEnded(r.Url, req)
+			return
+		}
+	}
+}
+
+// reqSubs is the subscriptions a REQ carries, which a replan may change
+// while it is open
+func (m *subManager) reqSubs(req *relayReq) []*logicalSub {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return req.subs
+}
+
+// reqEnded drops a REQ that stopped without us closing it, such as when the
+// connection went, and tries again later
+func (p *Pool) reqEnded(url string, req *relayReq) {
+	m := p.subs
+	m.mu.Lock()
+	if req.ended {
+		m.mu.Unlock()
+		return
+	}
+	req.ended = true
+	open := m.reqs[url]
+	for i, r := range open {
+		if r == req {
+			m.reqs[url] = append(open[:i:i], open[i+1:]...)
+			break
+		}
+	}
+	m.mu.Unlock()
+	req.cancel()
+	p.resubscribeAfter(RESUBSCRIBE_DELAY)
+}
+
+func (ls *logicalSub) deliver(url string, ev *nostr.Event) {
+	ls.mu.Lock()
+	closed, live := ls.closed, ls.live[url]
+	ls.mu.Unlock()
+	if closed || !ls.filter.Matches(ev) || !ls.dedup.first(ev.ID) {
+		return
+	}
+	ls.handler(ev, live)
+}
+
+type plannedReq struct {
+	key     string
+	filters nostr.Filters
+	subs    []*logicalSub
+}
+
+// planReqs merges the subscriptions' filters where it can and packs them
+// into as few REQs as the relay's limits allow. The subscriptions that do not
+// fit are returned as well. A REQ is keyed by its filters alone.
+func planReqs(r *Relay, logical []*logicalSub) ([]plannedReq, []*logicalSub) {
+	type plannedFilter struct {
+		filter nostr.Filter
+		subs   []*logicalSub
+	}
+	merged := []*plannedFilter{}
+	for _, ls := range logical {
+		done := false
+		for _, pf := range merged {
+			if f, ok := mergeFilters(pf.filter, ls.filter); ok {
+				pf.filter = f
+				pf.subs = append(pf.subs, ls)
+				done = true
+				break
+			}
+		}
+		if !done {
+			merged = append(merged, &plannedFilter{filter: ls.filter, subs: []*logicalSub{ls}})
+		}
+	}
+
+	maxFilters := r.MaxFilters()
+	if maxFilters <= 0 {
+		maxFilters = DEFAULT_MAX_FILTERS
+	}
+	maxReqs := r.MaxSubscriptions()
+	if maxReqs <= 0 {
+		maxReqs = DEFAULT_MAX_SUBSCRIPTIONS
+	}
+	maxReqs -= QUERY_RESERVE
+	if maxReqs < 1 {
+		maxReqs = 1
+	}
+
+	planned := []plannedReq{}
+	dropped := []*logicalSub{}
+	for i := 0; i < len(merged); i += maxFilters {
+		if len(planned) == maxReqs {
+			log.Warn().Msgf("Relay %s allows %d subscriptions, %d filters left out",
+				r.Url, maxReqs, len(merged)-i)
+			for _, pf := range merged[i:] {
+				dropped = append(dropped, pf.subs...)
+			}
+			break
+		}
+		end := i + maxFilters
+		if end > len(merged) {
+			end = len(merged)
+		}
+		req := plannedReq{filters: nostr.Filters{}, subs: []*logicalSub{}}
+		for _, pf := range merged[i:end] {
+			req.filters = append(req.filters, pf.filter)
+			req.subs = append(req.subs, pf.subs...)
+		}
+		j, _ := json.Marshal(req.filters)
+		req.key = string(j)
+		planned = append(planned, req)
+	}
+	return planned, dropped
+}
+
+// mergeFilters joins two filters that differ only in authors and since,
+// taking the older since. Events are matched against each subscription's
+// own filter again, so the wider REQ is harmless.
+func mergeFilters(a, b nostr.Filter) (nostr.Filter, bool) {
+	if a.Authors == nil || b.Authors == nil || a.IDs != nil || b.IDs != nil ||
+		a.Limit != 0 || b.Limit != 0 || a.Until != nil || b.Until != nil {
+		return a, false
+	}
+	x, y := a, b
+	x.Authors, y.Authors = nil, nil
+	x.Since, y.Since = nil, nil
+	if !nostr.FilterEqual(x, y) {
+		return a, false
+	}
+
+	authors := append([]string{}, a.Authors...)
+	seen := map[string]bool{}
+	for _, pk := range a.Authors {
+		seen[pk] = true
+	}
+	for _, pk := range b.Authors {
+		if !seen[pk] {
+			seen[pk] = true
+			authors = append(authors, pk)
+		}
+	}
+	if len(authors) > MAX_MERGED_AUTHORS {
+		return a, false
+	}
+	merged := a
+	merged.Authors = authors
+	if a.Since == nil || b.Since == nil {
+		merged.Since = nil
+	} else if *b.Since < *a.Since {
+		merged.Since = b.Since
+	}
+	return merged, true
+}
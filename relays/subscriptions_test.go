@@ -0,0 +1,133 @@
+package relays
+
+import (
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"reflect"
+	"testing"
+)
+
+func testRelay(maxSubs, maxFilters int) *Relay {
+	r := NewRelay()
+	r.Url = "wss://relay.test"
+	r.relayMeta.Limitation.MaxSubscriptions = maxSubs
+	r.relayMeta.Limitation.MaxFilters = maxFilters
+	return r
+}
+
+func testSub(handle SubHandle, f nostr.Filter) *logicalSub {
+	return &logicalSub{handle: handle, filter: f, dedup: newEventDedup(), live: map[string]bool{}}
+}
+
+func TestMergeFilters(t *testing.T) {
+	older, newer := nostr.Timestamp(100), nostr.Timestamp(200)
+	notes := []int{nostr.KindTextNote}
+
+	merged, ok := mergeFilters(
+		nostr.Filter{Authors: []string{"a", "b"}, Kinds: notes, Since: &newer},
+		nostr.Filter{Authors: []string{"b", "c"}, Kinds: notes, Since: &older})
+	if !ok {
+		t.Fatal("Filters differing in authors and since were not merged")
+	}
+	if !reflect.DeepEqual(merged.Authors, []string{"a", "b", "c"}) {
+		t.Errorf("Authors %v, want a b c", merged.Authors)
+	}
+	if merged.Since == nil || *merged.Since != older {
+		t.Errorf("Since %v, want the older %d", merged.Since, older)
+	}
+
+	merged, _ = mergeFilters(
+		nostr.Filter{Authors: []string{"a"}, Kinds: notes, Since: &newer},
+		nostr.Filter{Authors: []string{"b"}, Kinds: notes})
+	if merged.Since != nil {
+		t.Errorf("Since %d, want none when either filter has none", *merged.Since)
+	}
+
+	apart := []struct {
+		name string
+		a, b nostr.Filter
+	}{
+		{"kinds", nostr.Filter{Authors: []string{"a"}, Kinds: notes}, nostr.Filter{Authors: []string{"b"}, Kinds: []int{nostr.KindReaction}}},
+		{"no authors", nostr.Filter{Kinds: notes}, nostr.Filter{Authors: []string{"b"}, Kinds: notes}},
+		{"limit", nostr.Filter{Authors: []string{"a"}, Limit: 10}, nostr.Filter{Authors: []string{"b"}}},
+		{"until", nostr.Filter{Authors: []string{"a"}, Until: &older}, nostr.Filter{Authors: []string{"b"}}},
+		{"ids", nostr.Filter{Authors: []string{"a"}, IDs: []string{"x"}}, nostr.Filter{Authors: []string{"b"}}},
+		{"tags", nostr.Filter{Authors: []string{"a"}, Tags: nostr.TagMap{"p": {"x"}}}, nostr.Filter{Authors: []string{"b"}}},
+	}
+	for _, tt := range apart {
+		if _, ok := mergeFilters(tt.a, tt.b); ok {
+			t.Errorf("%s: filters were merged", tt.name)
+		}
+	}
+
+	many := make([]string, MAX_MERGED_AUTHORS)
+	for i := range many {
+		many[i] = fmt.Sprint(i)
+	}
+	if _, ok := mergeFilters(nostr.Filter{Authors: many}, nostr.Filter{Authors: []string{"extra"}}); ok {
+		t.Error("Merged past MAX_MERGED_AUTHORS")
+	}
+}
+
+func TestPlanReqsMergesAndPacks(t *testing.T) {
+	notes := []int{nostr.KindTextNote}
+	feedA := testSub(1, nostr.Filter{Authors: []string{"a"}, Kinds: notes})
+	feedB := testSub(2, nostr.Filter{Authors: []string{"b"}, Kinds: notes})
+	mentions := testSub(3, nostr.Filter{Kinds: notes, Tags: nostr.TagMap{"p": {"me"}}})
+
+	plan, dropped := planReqs(testRelay(0, 0), []*logicalSub{feedA, feedB, mentions})
+	if len(dropped) != 0 {
+		t.Errorf("%d subscriptions dropped, want none", len(dropped))
+	}
+	if len(plan) != 1 {
+		t.Fatalf("%d REQs planned, want 1", len(plan))
+	}
+	if len(plan[0].filters) != 2 {
+		t.Errorf("%d filters, want the feeds merged into one plus mentions", len(plan[0].filters))
+	}
+	if !reflect.DeepEqual(plan[0].subs, []*logicalSub{feedA, feedB, mentions}) {
+		t.Error("REQ does not carry every subscription")
+	}
+
+	// One filter per REQ spreads them over REQs
+	plan, _ = planReqs(testRelay(0, 1), []*logicalSub{feedA, feedB, mentions})
+	if len(plan) != 2 {
+		t.Errorf("%d REQs planned with one filter each, want 2", len(plan))
+	}
+}
+
+func TestPlanReqsKeyIsFilters(t *testing.T) {
+	f := nostr.Filter{Kinds: []int{nostr.KindTextNote}, Tags: nostr.TagMap{"p": {"me"}}}
+	first, _ := planReqs(testRelay(0, 0), []*logicalSub{testSub(1, f)})
+	again, _ := planReqs(testRelay(0, 0), []*logicalSub{testSub(7, f)})
+	if first[0].key != again[0].key {
+		t.Errorf("Keys %s and %s differ for the same filters", first[0].key, again[0].key)
+	}
+}
+
+func TestPlanReqsReportsDropped(t *testing.T) {
+	logical := []*logicalSub{}
+	for i := 0; i < 4; i++ {
+		f := nostr.Filter{Kinds: []int{nostr.KindTextNote}, Tags: nostr.TagMap{"p": {string(rune('a' + i))}}}
+		logical = append(logical, testSub(SubHandle(i+1), f))
+	}
+	// Two REQs after the query reserve, one filter each
+	plan, dropped := planReqs(testRelay(QUERY_RESERVE+2, 1), logical)
+	if len(plan) != 2 {
+		t.Fatalf("%d REQs planned, want 2", len(plan))
+	}
+	if !reflect.DeepEqual(dropped, logical[2:]) {
+		t.Errorf("Dropped %d subscriptions, want the last 2", len(dropped))
+	}
+}
+
+func TestGainsSubs(t *testing.T) {
+	a := testSub(1, nostr.Filter{})
+	b := testSub(2, nostr.Filter{})
+	if gainsSubs([]*logicalSub{a, b}, []*logicalSub{a}) {
+		t.Error("Losing a subscription counted as gaining one")
+	}
+	if !gainsSubs([]*logicalSub{a}, []*logicalSub{a, b}) {
+		t.Error("New subscription not noticed")
+	}
+}
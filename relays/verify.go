@@ -0,0 +1,129 @@
+package relays
+
+import (
+	"errors"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+	"sync"
+)
+
+// Verified signatures remembered before the oldest half is dropped
+const VERIFIED_CACHE_SIZE = 50000
+
+var (
+	errBadEventId  = errors.New("event id does not match its content")
+	errBadEventSig = errors.New("invalid event signature")
+)
+
+// verifiedCache remembers which id/signature pairs have passed verification,
+// so copies of an event from other relays skip the expensive check. Two
+// generations keep it bounded without tracking age per entry.
+type verifiedCache struct {
+	mu       sync.Mutex
+	current  map[string]bool
+	previous map[string]bool
+}
+
+func newVerifiedCache() *verifiedCache {
+	return &verifiedCache{
+		current:  make(map[string]bool),
+		previous: make(map[string]bool),
+	}
+}
+
+func (c *verifiedCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current[key] || c.previous[key]
+}
+
+func (c *verifiedCache) add(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.current) >= VERIFIED_CACHE_SIZE/2 {
+		c.previous = c.current
+		c.current = make(map[string]bool)
+	}
+	c.current[key] = true
+}
+
+// verifyEvent checks the id is the hash of the event and the signature is
+// valid for it. The id is always recomputed, as it is cheap and stops a relay
+// pairing a known good signature with other content.
+func (c *verifiedCache) verifyEvent(ev *nostr.Event) error {
+	if ev.GetID() != ev.ID {
+		return errBadEventId
+	}
+	key := ev.ID + ev.Sig
+	if c.has(key) {
+		return nil
+	}
+	ok, err := ev.CheckSignature()
+	if err != nil || !ok {
+		return errBadEventSig
+	}
+	c.add(key)
+	return nil
+}
+
+// eventDedup passes through the first copy of each event, for one query or
+// subscription across all of its relays
+type eventDedup struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newEventDedup() *eventDedup {
+	return &eventDedup{seen: make(map[string]bool)}
+}
+
+func (d *eventDedup) first(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen[id] {
+		return false
+	}
+	d.seen[id] = true
+	return true
+}
+
+// accept runs an event from a relay through verification then deduplication,
+// returning true if it should be passed on. Rejects are counted per relay.
+func (p *Pool) accept(url string, ev *nostr.Event, dedup *eventDedup) bool {
+	if ev == nil {
+		return false
+	}
+	// Copies of an event already verified only cost an id check, and still
+	// have to pass it before the relay is credited with the event
+	err := p.verified.verifyEvent(ev)
+	if err != nil {
+		p.countReject(url)
+		log.Warn().Msgf("Rejected event %s from %s: %s", ev.ID, url, err.Error())
+		return false
+	}
+	p.provenance.seen(ev.ID, url)
+	return dedup == nil || dedup.first(ev.ID)
+}
+
+// Verify checks the id and signature of an event that did not come from a
+// relay in the pool, such as one written to the local relay
+func (p *Pool) Verify(ev *nostr.Event) error {
+	return p.verified.verifyEvent(ev)
+}
+
+func (p *Pool) countReject(url string) {
+	p.rejectMu.Lock()
+	defer p.rejectMu.Unlock()
+	p.rejects[url]++
+}
+
+// GetRejectCounts returns how many invalid events each relay has sent
+func (p *Pool) GetRejectCounts() map[string]int64 {
+	p.rejectMu.Lock()
+	defer p.rejectMu.Unlock()
+	counts := make(map[string]int64)
+	for url, n := range p.rejects {
+		counts[url] = n
+	}
+	return counts
+}
@@ -0,0 +1,60 @@
+package relays
+
+import (
+	"fmt"
+	"github.com/nbd-wtf/go-nostr"
+	"testing"
+)
+
+// Copies of each event, as from this many relays
+const BENCH_RELAYS = 5
+
+func signedNotes(b *testing.B, n int) []*nostr.Event {
+	b.Helper()
+	sk := nostr.GeneratePrivateKey()
+	pk, _ := nostr.GetPublicKey(sk)
+	evs := make([]*nostr.Event, n)
+	for i := range evs {
+		ev := nostr.Event{
+			PubKey:    pk,
+			CreatedAt: nostr.Now(),
+			Kind:      nostr.KindTextNote,
+			Tags:      nostr.Tags{},
+			Content:   fmt.Sprintf("Benchmark note %d", i),
+		}
+		if err := ev.Sign(sk); err != nil {
+			b.Fatal(err)
+		}
+		evs[i] = &ev
+	}
+	return evs
+}
+
+// BenchmarkVerification times the verify and dedup stages with the same
+// signed events arriving from several relays, the common case for a large
+// follow list. With one subscription copies are dropped by dedup; with
+// separate ones the verified cache is what saves the work.
+func BenchmarkVerification(b *testing.B) {
+	for _, shared := range []bool{true, false} {
+		name := "verified cache"
+		if shared {
+			name = "first seen"
+		}
+		b.Run(name, func(b *testing.B) {
+			evs := signedNotes(b, b.N)
+			rp := NewPool()
+			dedup := newEventDedup()
+			b.ResetTimer()
+			for _, ev := range evs {
+				if !shared {
+					dedup = newEventDedup()
+				}
+				for r := 0; r < BENCH_RELAYS; r++ {
+					// Each relay delivers its own copy
+					copied := *ev
+					rp.accept(fmt.Sprintf("wss://relay%d.example", r), &copied, dedup)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Package storage is the in-memory cache of events, profiles and
+// notifications.
+package storage
+
+import (
+	"fmt"
+	"github.com/arriqaaq/hash"
+	"github.com/nbd-wtf/go-nostr"
+	"greet/domain"
+	"sync"
+)
+
+type DB struct {
+	cache     *hash.Hash
+	mu        sync.Mutex
+	listeners map[int]func(ev *nostr.Event)
+	nextId    int
+}
+
+const (
+	EVENT   = "event"
+	META    = "meta"
+	NOTIFY  = "notify"
+	ADDR    = "addr"
+	DELETED = "deleted"
+)
+
+func NewDB() *DB {
+	return &DB{
+		cache:     hash.New(),
+		listeners: map[int]func(ev *nostr.Event){},
+	}
+}
+
+func (p *DB) GetLock() {
+	p.mu.Lock()
+}
+
+func (p *DB) ReleaseLock() {
+	p.mu.Unlock()
+}
+
+func (p *DB) HasEvent(evId string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cache.HExists(EVENT, evId)
+}
+
+func (p *DB) HasProfile(pk string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cache.HExists(META, pk)
+}
+
+func (p *DB) GetEvent(evId string) *nostr.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.cache.HGet(EVENT, evId)
+	if r == nil {
+		return nil
+	}
+	return r.(*nostr.Event)
+}
+
+func (p *DB) GetProfile(pk string) *domain.Profile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.cache.HGet(META, pk)
+	if r == nil {
+		return nil
+	}
+	return r.(*domain.Profile)
+}
+
+func (p *DB) AddProfile(pk string, profile *domain.Profile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cache.HSet(META, pk, profile)
+}
+
+// AddEvent stores an event, returning false if it has been deleted or is a
+// replaceable or addressable event older than the version already held
+func (p *DB) AddEvent(evId string, event *nostr.Event) bool {
+	p.mu.Lock()
+	isNew := !p.cache.HExists(EVENT, evId)
+	stored := p.addEvent(evId, event)
+	listeners := []func(ev *nostr.Event){}
+	if stored && isNew {
+		for _, fn := range p.listeners {
+			listeners = append(listeners, fn)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(event)
+	}
+	return stored
+}
+
+func (p *DB) addEvent(evId string, event *nostr.Event) bool {
+	if p.isDeleted(event) {
+		return false
+	}
+	if domain.IsReplaceableKind(event.Kind) || domain.IsAddressableKind(event.Kind) {
+		return p.replaceEvent(domain.EventAddress(event), event)
+	}
+	p.cache.HSet(EVENT, evId, event)
+	return true
+}
+
+// AddListener calls fn with each event added to the cache for the first
+// time, outside the lock. The returned id removes it again.
+func (p *DB) AddListener(fn func(ev *nostr.Event)) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextId++
+	p.listeners[p.nextId] = fn
+	return p.nextId
+}
+
+func (p *DB) RemoveListener(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.listeners, id)
+}
+
+// replaceEvent stores event as the version for addr, unless the version we
+// already hold is newer. The superseded version is dropped. Callers hold the
+// lock.
+func (p *DB) replaceEvent(addr string, event *nostr.Event) bool {
+	if r := p.cache.HGet(ADDR, addr); r != nil {
+		current := p.cache.HGet(EVENT, r.(string))
+		if current != nil {
+			ev := current.(*nostr.Event)
+			if ev.CreatedAt > event.CreatedAt || (ev.CreatedAt == event.CreatedAt && ev.ID <= event.ID) {
+				return false
+			}
+			p.cache.HDel(EVENT, ev.ID)
+		}
+	}
+	p.cache.HSet(ADDR, addr, event.ID)
+	p.cache.HSet(EVENT, event.ID, event)
+	return true
+}
+
+// GetReplaceableEvent returns the newest version we hold of a replaceable
+// event, or of an addressable one when d is given
+func (p *DB) GetReplaceableEvent(pk string, kind int, d string) *nostr.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.cache.HGet(ADDR, fmt.Sprintf("%d:%s:%s", kind, pk, d))
+	if r == nil {
+		return nil
+	}
+	ev := p.cache.HGet(EVENT, r.(string))
+	if ev == nil {
+		return nil
+	}
+	return ev.(*nostr.Event)
+}
+
+// AddDeletion records a NIP-09 deletion of an event id or kind:pubkey:d
+// address and drops what it deletes. Returns the ids removed. Deletions are
+// kept per deleter, so one from someone else never replaces the author's.
+func (p *DB) AddDeletion(ref string, deletion *nostr.Event) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := deletionKey(ref, deletion.PubKey)
+	if r := p.cache.HGet(DELETED, key); r == nil || r.(*nostr.Event).CreatedAt < deletion.CreatedAt {
+		p.cache.HSet(DELETED, key, deletion)
+	}
+
+	removed := []string{}
+	id := ref
+	if r := p.cache.HGet(ADDR, ref); r != nil {
+		id = r.(string)
+	}
+	if r := p.cache.HGet(EVENT, id); r != nil && p.isDeleted(r.(*nostr.Event)) {
+		p.cache.HDel(EVENT, id)
+		removed = append(removed, id)
+	}
+	return removed
+}
+
+// isDeleted checks an event against the deletions seen. Only the author can
+// delete, and deleting an address only covers versions up to the deletion.
+// Callers hold the lock.
+func (p *DB) isDeleted(event *nostr.Event) bool {
+	if p.cache.HExists(DELETED, deletionKey(event.ID, event.PubKey)) {
+		return true
+	}
+	if domain.IsReplaceableKind(event.Kind) || domain.IsAddressableKind(event.Kind) {
+		r := p.cache.HGet(DELETED, deletionKey(domain.EventAddress(event), event.PubKey))
+		if r != nil && event.CreatedAt <= r.(*nostr.Event).CreatedAt {
+			return true
+		}
+	}
+	return false
+}
+
+// deletionKey is where the deletion of ref by pk is kept
+func deletionKey(ref string, pk string) string {
+	return ref + "/" + pk
+}
+
+func (p *DB) IsDeleted(event *nostr.Event) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.isDeleted(event)
+}
+
+func (p *DB) HasNotification(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cache.HExists(NOTIFY, id)
+}
+
+// AddNotification stores a copy of n, so the caller's stays unchanged when
+// it is marked read
+func (p *DB) AddNotification(n *domain.Notification) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stored := *n
+	p.cache.HSet(NOTIFY, n.Id, &stored)
+}
+
+func (p *DB) GetNotification(id string) *domain.Notification {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.cache.HGet(NOTIFY, id)
+	if r == nil {
+		return nil
+	}
+	n := *r.(*domain.Notification)
+	return &n
+}
+
+// GetNotifications returns copies taken under the lock, as Read changes
+func (p *DB) GetNotifications() []*domain.Notification {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ns := []*domain.Notification{}
+	for _, v := range p.cache.HVals(NOTIFY) {
+		n := *v.(*domain.Notification)
+		ns = append(ns, &n)
+	}
+	return ns
+}
+
+func (p *DB) MarkNotificationRead(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.cache.HGet(NOTIFY, id)
+	if r != nil {
+		r.(*domain.Notification).Read = true
+	}
+}
+
+// QueryEvents returns the cached events matching the filter
+func (p *DB) QueryEvents(f *nostr.Filter) []*nostr.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := []*nostr.Event{}
+	for _, v := range p.cache.HVals(EVENT) {
+		ev := v.(*nostr.Event)
+		if f.Matches(ev) {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func (p *DB) DumpEvents() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	//entries := p.cache.HGetAll(EVENT)
+	keys := p.cache.HKeys(EVENT)
+
+	fmt.Println("Dumping", len(keys), "keys")
+	for i := 0; i < len(keys); i++ {
+		key := keys[i]
+		fmt.Println(key)
+	}
+}